@@ -2,15 +2,24 @@ package main
 
 import (
 	balance "Simulations/src/balance"
+	"Simulations/src/cache"
+	"Simulations/src/compiler"
 	"Simulations/src/debug"
+	"Simulations/src/etherscan"
 	"Simulations/src/fork"
+	"Simulations/src/jobs"
+	"Simulations/src/localsources"
 	evm "Simulations/src/rpc"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/labstack/echo"
 )
 
@@ -24,14 +33,20 @@ type Controller struct {
 	evmService     *evm.Service
 	balanceService *balance.Service
 	debugService   *debug.Service
+	jobsService    *jobs.Service
+	cacheService   *cache.Service
+	localSources   *localsources.Service
 }
 
-func NewController(forkService *fork.Service, evmService *evm.Service, balanceService *balance.Service, debugService *debug.Service) *Controller {
+func NewController(forkService *fork.Service, evmService *evm.Service, balanceService *balance.Service, debugService *debug.Service, jobsService *jobs.Service, cacheService *cache.Service, localSources *localsources.Service) *Controller {
 	return &Controller{
 		forkService:    forkService,
 		evmService:     evmService,
 		balanceService: balanceService,
 		debugService:   debugService,
+		jobsService:    jobsService,
+		cacheService:   cacheService,
+		localSources:   localSources,
 	}
 }
 
@@ -163,7 +178,7 @@ func (ctrl *Controller) getERC20BalanceHandler(c echo.Context) error {
 	userAddress := c.QueryParam("address")
 	tokenAddress := c.QueryParam("tokenAddress")
 
-	balance, err := ctrl.balanceService.GetERC20Balance(forkId, tokenAddress, userAddress)
+	balance, err := ctrl.balanceService.GetFormattedERC20Balance(forkId, tokenAddress, userAddress)
 	if err != nil {
 		httpError := HTTPError{
 			Message: "Error getting ERC20 balance",
@@ -195,6 +210,79 @@ func (ctrl *Controller) setERC20BalanceHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, "Balance successfully changed to: "+balance)
 }
 
+func (ctrl *Controller) getERC20AllowanceHandler(c echo.Context) error {
+	forkId := c.Param("forkId")
+	ownerAddress := c.QueryParam("owner")
+	spenderAddress := c.QueryParam("spender")
+	tokenAddress := c.QueryParam("tokenAddress")
+
+	allowance, err := ctrl.balanceService.GetERC20Allowance(forkId, tokenAddress, ownerAddress, spenderAddress)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error getting ERC20 allowance",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, allowance)
+}
+
+func (ctrl *Controller) setERC20AllowanceHandler(c echo.Context) error {
+	forkId := c.Param("forkId")
+	ownerAddress := c.QueryParam("owner")
+	spenderAddress := c.QueryParam("spender")
+	tokenAddress := c.QueryParam("tokenAddress")
+	allowance := c.QueryParam("allowance")
+
+	err := ctrl.balanceService.SetERC20Allowance(forkId, ownerAddress, spenderAddress, tokenAddress, allowance)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error setting ERC20 allowance",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, "Allowance successfully changed to: "+allowance)
+}
+
+func (ctrl *Controller) getTokenMetadataHandler(c echo.Context) error {
+	forkId := c.Param("forkId")
+	tokenAddress := c.QueryParam("tokenAddress")
+
+	metadata, err := ctrl.balanceService.GetTokenMetadata(forkId, tokenAddress)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error getting token metadata",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, metadata)
+}
+
+func (ctrl *Controller) getPortfolioHandler(c echo.Context) error {
+	forkId := c.Param("forkId")
+	userAddress := c.Param("address")
+
+	portfolio, err := ctrl.balanceService.GetPortfolio(forkId, userAddress)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error getting portfolio",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, portfolio)
+}
+
 func (ctrl *Controller) getSourceCode(c echo.Context) error {
 	contractAddress := c.QueryParam("contractAddress")
 
@@ -232,30 +320,297 @@ func (ctrl *Controller) debugTransactionCallTraceHandler(c echo.Context) error {
 	forkId := c.Param("forkId")
 	txHash := c.QueryParam("txHash")
 
-	errorLineNumber, errorMessage, debugCallTrace, err := ctrl.debugService.DebugTransaction(forkId, txHash)
+	res, err := ctrl.debugService.DebugTransaction(forkId, txHash)
 	if err != nil {
 		httpError := HTTPError{
-			Message: "Error debugging transaction",
+			Message: debugErrorMessage("Error debugging transaction", err),
 			Status:  http.StatusInternalServerError,
 		}
 
 		return c.JSON(http.StatusInternalServerError, httpError)
 	}
 
-	res := struct {
-		RevertReason string
-		LineNumber   int
-		DebugTrace   []debug.CallTrace
-	}{
-		RevertReason: errorMessage,
-		LineNumber:   errorLineNumber,
-		DebugTrace:   debugCallTrace,
+	return c.JSON(http.StatusOK, res)
+}
+
+func (ctrl *Controller) debugHistoricalTransactionHandler(c echo.Context) error {
+	txHash := c.Param("txHash")
+
+	res, err := ctrl.debugService.DebugHistoricalTransaction(txHash)
+	if err != nil {
+		httpError := HTTPError{
+			Message: debugErrorMessage("Error debugging transaction", err),
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
 	}
 
 	return c.JSON(http.StatusOK, res)
 }
 
-func (ctrl *Controller) simulateRawTxHandler(c echo.Context) error {
+func (ctrl *Controller) gasProfileHandler(c echo.Context) error {
+	forkId := c.Param("forkId")
+	txHash := c.QueryParam("txHash")
+	format := c.QueryParam("format")
+
+	profile, err := ctrl.debugService.GetGasProfile(forkId, txHash)
+	if err != nil {
+		httpError := HTTPError{
+			Message: debugErrorMessage("Error profiling transaction gas", err),
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	if format == "folded" {
+		return c.String(http.StatusOK, profile.FoldedStacks())
+	}
+
+	return c.JSON(http.StatusOK, profile)
+}
+
+func (ctrl *Controller) verifyBytecodeHandler(c echo.Context) error {
+	address := c.Param("address")
+	forkId := c.QueryParam("forkId")
+
+	if !common.IsHexAddress(address) {
+		httpError := HTTPError{
+			Message: "Invalid address",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	report, err := ctrl.debugService.VerifyBytecode(forkId, address)
+	if errors.Is(err, etherscan.ErrNotVerified) {
+		httpError := HTTPError{
+			Message: "Contract source code is not verified",
+			Status:  http.StatusNotFound,
+		}
+
+		return c.JSON(http.StatusNotFound, httpError)
+	}
+	if err != nil {
+		httpError := HTTPError{
+			Message: debugErrorMessage("Error verifying bytecode", err),
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func (ctrl *Controller) listLocalSourcesHandler(c echo.Context) error {
+	entries, err := ctrl.localSources.List()
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error listing local sources",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
+
+// Registers a standard JSON input, a Foundry artifact or a Hardhat build info file as the sources of an address
+func (ctrl *Controller) registerLocalSourcesHandler(c echo.Context) error {
+	address := c.Param("address")
+
+	if !common.IsHexAddress(address) {
+		httpError := HTTPError{
+			Message: "Invalid address",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Bad request format",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	var registration localsources.Registration
+	err = json.Unmarshal(body, &registration)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Bad request format",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	localSource, err := ctrl.localSources.Register(address, registration)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Invalid sources: " + err.Error(),
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	// Artifacts compiled from other sources must not be used anymore
+	ctrl.purgeAddressCache(address)
+
+	return c.JSON(http.StatusOK, localsources.Entry{
+		Address:         localSource.Address,
+		Kind:            localSource.Kind,
+		ContractName:    localSource.Info.ContractName,
+		CompilerVersion: localSource.Info.CompilerVersion,
+		RegisteredAt:    localSource.RegisteredAt,
+	})
+}
+
+func (ctrl *Controller) deleteLocalSourcesHandler(c echo.Context) error {
+	address := c.Param("address")
+
+	if !common.IsHexAddress(address) {
+		httpError := HTTPError{
+			Message: "Invalid address",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	deleted, err := ctrl.localSources.Delete(address)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error deleting local sources",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	if !deleted {
+		httpError := HTTPError{
+			Message: "No local sources registered for this address",
+			Status:  http.StatusNotFound,
+		}
+
+		return c.JSON(http.StatusNotFound, httpError)
+	}
+
+	ctrl.purgeAddressCache(address)
+
+	return c.JSON(http.StatusOK, "Successfully deleted local sources")
+}
+
+func (ctrl *Controller) purgeAddressCache(address string) {
+	_, err := ctrl.cacheService.Purge(address, "")
+	if err != nil {
+		fmt.Printf("⚠️  Failed to purge cache of %s: %v\n", address, err)
+	}
+}
+
+func (ctrl *Controller) createStepSessionHandler(c echo.Context) error {
+	forkId := c.Param("forkId")
+	txHash := c.QueryParam("txHash")
+	includeMemory := c.QueryParam("memory") == "true"
+
+	sessionInfo, err := ctrl.debugService.CreateStepSession(forkId, txHash, includeMemory)
+	if err != nil {
+		httpError := HTTPError{
+			Message: debugErrorMessage("Error creating step session", err),
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusCreated, sessionInfo)
+}
+
+func (ctrl *Controller) getStepsHandler(c echo.Context) error {
+	sessionId := c.Param("sessionId")
+	cursorParam := c.QueryParam("cursor")
+	limitParam := c.QueryParam("limit")
+
+	if cursorParam == "" {
+		cursorParam = "0"
+	}
+
+	if limitParam == "" {
+		limitParam = "100"
+	}
+
+	cursor, err := strconv.Atoi(cursorParam)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Invalid cursor",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Invalid limit",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	page, err := ctrl.debugService.GetSteps(sessionId, cursor, limit)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error getting steps",
+			Status:  http.StatusNotFound,
+		}
+
+		return c.JSON(http.StatusNotFound, httpError)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+func (ctrl *Controller) getVariablesHandler(c echo.Context) error {
+	sessionId := c.Param("sessionId")
+
+	step, err := strconv.Atoi(c.QueryParam("step"))
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Invalid step",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	variables, err := ctrl.debugService.GetVariables(sessionId, step)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error getting variables",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, variables)
+}
+
+func (ctrl *Controller) navigateHandler(c echo.Context) error {
+	sessionId := c.Param("sessionId")
+
 	rawData, err := io.ReadAll(c.Request().Body)
 	if err != nil {
 		httpError := HTTPError{
@@ -266,30 +621,372 @@ func (ctrl *Controller) simulateRawTxHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, httpError)
 	}
 
-	// Get optional block number parameter
-	blockNumber := c.QueryParam("blockNumber")
+	var request debug.NavigationRequest
+	err = json.Unmarshal(rawData, &request)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Bad request format",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	result, err := ctrl.debugService.Navigate(sessionId, request)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error navigating steps",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func (ctrl *Controller) deleteStepSessionHandler(c echo.Context) error {
+	sessionId := c.Param("sessionId")
+
+	ctrl.debugService.DeleteStepSession(sessionId)
+
+	return c.JSON(http.StatusOK, fmt.Sprintf("Successfully deleted step session: %v", sessionId))
+}
+
+func (ctrl *Controller) simulateRawTxHandler(c echo.Context) error {
+	request, httpError := parseSimulationRequest(c)
+	if httpError != nil {
+		return c.JSON(httpError.Status, httpError)
+	}
 
-	contractsCalled, errorLineNumber, revertReason, debugTrace, err := ctrl.debugService.SimulateRawTransaction(rawData, blockNumber)
+	res, err := ctrl.debugService.SimulateRawTransaction(request.RawData, request.BlockNumber, request.TxIndex, request.StateOverrides)
 	if err != nil {
 		htppError := HTTPError{
-			Message: "Error simulating raw transaction",
+			Message: debugErrorMessage("Error simulating raw transaction", err),
 			Status:  http.StatusInternalServerError,
 		}
 
 		return c.JSON(http.StatusInternalServerError, htppError)
 	}
 
-	res := struct {
-		ContractsCalled []debug.ContractCalled
-		LineNumber   int
-		RevertReason string
-		DebugTrace   []debug.CallTrace
-	}{
-		ContractsCalled: contractsCalled,
-		LineNumber:   errorLineNumber,
-		RevertReason: revertReason,
-		DebugTrace:   debugTrace,
+	return c.JSON(http.StatusOK, res)
+}
+
+func (ctrl *Controller) submitSimulationHandler(c echo.Context) error {
+	request, httpError := parseSimulationRequest(c)
+	if httpError != nil {
+		return c.JSON(httpError.Status, httpError)
+	}
+
+	job, err := ctrl.jobsService.SubmitSimulation(request)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Too many queued simulations",
+			Status:  http.StatusServiceUnavailable,
+		}
+
+		return c.JSON(http.StatusServiceUnavailable, httpError)
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func (ctrl *Controller) getJobHandler(c echo.Context) error {
+	jobId := c.Param("jobId")
+
+	job, err := ctrl.jobsService.GetJob(jobId)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Job not found",
+			Status:  http.StatusNotFound,
+		}
+
+		return c.JSON(http.StatusNotFound, httpError)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+func (ctrl *Controller) cancelJobHandler(c echo.Context) error {
+	jobId := c.Param("jobId")
+
+	job, err := ctrl.jobsService.CancelJob(jobId)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Job not found",
+			Status:  http.StatusNotFound,
+		}
+
+		return c.JSON(http.StatusNotFound, httpError)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// Streams the events of a job as Server-Sent Events until it is over, resuming after Last-Event-ID
+func (ctrl *Controller) jobEventsHandler(c echo.Context) error {
+	jobId := c.Param("jobId")
+
+	after, err := strconv.Atoi(c.Request().Header.Get("Last-Event-ID"))
+	if err != nil {
+		after = 0
+	}
+
+	_, err = ctrl.jobsService.GetJob(jobId)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Job not found",
+			Status:  http.StatusNotFound,
+		}
+
+		return c.JSON(http.StatusNotFound, httpError)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	stop := c.Request().Context().Done()
+	for {
+		events, finished, err := ctrl.jobsService.NextEvents(jobId, after, stop, 15*time.Second)
+		if err != nil || finished {
+			return nil
+		}
+
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		// Comment line keeping idle connections open
+		if len(events) == 0 {
+			fmt.Fprint(res, ": keep-alive\n\n")
+			res.Flush()
+			continue
+		}
+
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+			after = event.Id
+		}
+		res.Flush()
+	}
+}
+
+// Debug errors stay generic, except for missing compilers which have to be installed by an operator
+func debugErrorMessage(message string, err error) string {
+	var missingCompiler *compiler.MissingCompilerError
+	if errors.As(err, &missingCompiler) {
+		return message + ": " + missingCompiler.Error()
+	}
+
+	return message
+}
+
+// Raw transaction request body with optional stateOverrides, blockNumber and txIndex query parameters
+func parseSimulationRequest(c echo.Context) (jobs.SimulationRequest, *HTTPError) {
+	badRequest := &HTTPError{
+		Message: "Bad request format",
+		Status:  http.StatusBadRequest,
+	}
+
+	rawData, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return jobs.SimulationRequest{}, badRequest
+	}
+
+	rpcRequest, stateOverrides, err := splitStateOverrides(rawData)
+	if err != nil {
+		return jobs.SimulationRequest{}, badRequest
+	}
+
+	err = stateOverrides.Validate()
+	if err != nil {
+		return jobs.SimulationRequest{}, &HTTPError{
+			Message: err.Error(),
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	// Get optional block number parameter
+	blockNumber := c.QueryParam("blockNumber")
+
+	// Get optional position in the block, the earlier transactions are replayed first
+	var txIndex *int
+	if txIndexParam := c.QueryParam("txIndex"); txIndexParam != "" {
+		index, err := strconv.Atoi(txIndexParam)
+		if err != nil {
+			return jobs.SimulationRequest{}, &HTTPError{
+				Message: "Invalid txIndex",
+				Status:  http.StatusBadRequest,
+			}
+		}
+		txIndex = &index
+	}
+
+	request := jobs.SimulationRequest{
+		RawData:        rpcRequest,
+		BlockNumber:    blockNumber,
+		TxIndex:        txIndex,
+		StateOverrides: stateOverrides,
+	}
+
+	return request, nil
+}
+
+func (ctrl *Controller) simulateCallHandler(c echo.Context) error {
+	rawData, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Bad request format",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	var simulation debug.CallSimulation
+	err = json.Unmarshal(rawData, &simulation)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Bad request format",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	res, err := ctrl.debugService.SimulateCall(simulation)
+	if err != nil {
+		httpError := HTTPError{
+			Message: debugErrorMessage("Error simulating call", err),
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (ctrl *Controller) simulateBundleHandler(c echo.Context) error {
+	rawData, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Bad request format",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	var bundle debug.BundleSimulation
+	err = json.Unmarshal(rawData, &bundle)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Bad request format",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	err = bundle.StateOverrides.Validate()
+	if err != nil {
+		httpError := HTTPError{
+			Message: err.Error(),
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	res, err := ctrl.debugService.SimulateBundle(bundle)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error simulating bundle",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
 	}
 
 	return c.JSON(http.StatusOK, res)
 }
+
+// State overrides are sent as an extra member of the JSON-RPC request
+func splitStateOverrides(rawData []byte) ([]byte, debug.StateOverride, error) {
+	var request map[string]json.RawMessage
+	err := json.Unmarshal(rawData, &request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rawOverrides, exists := request["stateOverrides"]
+	if !exists {
+		return rawData, nil, nil
+	}
+
+	var stateOverrides debug.StateOverride
+	err = json.Unmarshal(rawOverrides, &stateOverrides)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	delete(request, "stateOverrides")
+	rpcRequest, err := json.Marshal(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rpcRequest, stateOverrides, nil
+}
+
+func (ctrl *Controller) getCacheHandler(c echo.Context) error {
+	summary, err := ctrl.cacheService.List()
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error reading cache",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
+
+func (ctrl *Controller) purgeCacheHandler(c echo.Context) error {
+	address := c.QueryParam("address")
+	codeHash := c.QueryParam("codeHash")
+
+	if address == "" && codeHash != "" {
+		httpError := HTTPError{
+			Message: "codeHash requires an address",
+			Status:  http.StatusBadRequest,
+		}
+
+		return c.JSON(http.StatusBadRequest, httpError)
+	}
+
+	removed, err := ctrl.cacheService.Purge(address, codeHash)
+	if err != nil {
+		httpError := HTTPError{
+			Message: "Error purging cache",
+			Status:  http.StatusInternalServerError,
+		}
+
+		return c.JSON(http.StatusInternalServerError, httpError)
+	}
+
+	return c.JSON(http.StatusOK, fmt.Sprintf("Successfully purged %d cache entries", removed))
+}
@@ -3,15 +3,24 @@ package main
 import (
 	"Simulations/src/anvil"
 	balance "Simulations/src/balance"
+	"Simulations/src/cache"
+	"Simulations/src/compiler"
 	"Simulations/src/debug"
 	"Simulations/src/etherscan"
 	"Simulations/src/fork"
 	"Simulations/src/fork/db"
 	"Simulations/src/fork/dbRepo"
+	"Simulations/src/jobs"
+	"Simulations/src/localsources"
 	evm "Simulations/src/rpc"
+	"Simulations/src/signatures"
+	"Simulations/src/sources"
+	"Simulations/src/sourcify"
 
+	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo"
@@ -20,6 +29,22 @@ import (
 	"strings"
 )
 
+// Contracts without verified source are looked up again after this long
+const unverifiedCacheTtl = time.Hour
+
+// Number of ABIs kept in memory
+const abiCacheSize = 1000
+
+// Providers of verified sources, asked in this order unless configured
+const defaultSourceProviders = "etherscan,sourcify,blockscout"
+
+// Requests per second allowed to each provider unless configured, Etherscan's free tier allows 5
+var defaultSourceRateLimits = map[string]float64{
+	"etherscan":  5,
+	"sourcify":   5,
+	"blockscout": 5,
+}
+
 func main() {
 	if godotenv.Load() != nil {
 		panic("Failed loading .env file!")
@@ -28,6 +53,19 @@ func main() {
 	portsArg := os.Getenv("PORTS")
 	rpcUrl := os.Getenv("RPC_URL")
 	etherScanApiKey := os.Getenv("ETHERSCAN_API_KEY")
+	portfolioTokensArg := os.Getenv("PORTFOLIO_TOKENS")
+	simulationWorkersArg := os.Getenv("SIMULATION_WORKERS")
+	chainIdArg := os.Getenv("CHAIN_ID")
+	cacheDirArg := os.Getenv("CACHE_DIR")
+	solcDirArg := os.Getenv("SOLC_DIR")
+	solcMirrorArg := os.Getenv("SOLC_MIRROR")
+	// Vyper has no official archive in the list.json layout, binaries are only installed when a mirror is set
+	vyperMirrorArg := os.Getenv("VYPER_MIRROR")
+	localSourcesDirArg := os.Getenv("LOCAL_SOURCES_DIR")
+	sourceProvidersArg := os.Getenv("SOURCE_PROVIDERS")
+	sourceRateLimitsArg := os.Getenv("SOURCE_RATE_LIMITS")
+	sourcifyUrlArg := os.Getenv("SOURCIFY_URL")
+	blockscoutApiUrl := os.Getenv("BLOCKSCOUT_API_URL")
 
 	dbRepository := &dbRepo.Repository{}
 	err := dbRepository.Init()
@@ -42,11 +80,39 @@ func main() {
 	forkService.AllocatePorts(parsePorts(portsArg))
 
 	evmService := evm.NewService(forkService)
-	balanceService := balance.NewService(evmService)
-	etherscanService := etherscan.NewService(etherScanApiKey)
-	debugService := debug.NewService(forkService, etherscanService, evmService)
+	balanceService := balance.NewService(evmService, parseAddresses(portfolioTokensArg))
+	chainId := parseChainId(chainIdArg)
+	rateLimits := parseSourceRateLimits(sourceRateLimitsArg)
+	sourcesService := sources.NewService()
+	for _, provider := range parseSourceProviders(sourceProvidersArg) {
+		switch provider {
+		case "etherscan":
+			if etherScanApiKey == "" {
+				fmt.Println("⚠️  Skipping the etherscan source provider, ETHERSCAN_API_KEY is not set")
+				continue
+			}
+			sourcesService.AddProvider(provider, etherscan.NewService(etherScanApiKey, chainId), rateLimits[provider])
+		case "sourcify":
+			sourcesService.AddProvider(provider, sourcify.NewService(parseSourcifyUrl(sourcifyUrlArg), chainId), rateLimits[provider])
+		case "blockscout":
+			if blockscoutApiUrl == "" {
+				fmt.Println("⚠️  Skipping the blockscout source provider, BLOCKSCOUT_API_URL is not set")
+				continue
+			}
+			sourcesService.AddProvider(provider, etherscan.NewExplorerService(blockscoutApiUrl, "", ""), rateLimits[provider])
+		default:
+			panic("Bad source providers environment variable!")
+		}
+	}
+	fmt.Printf("📚 Source providers for chain %s: %s\n", chainId, strings.Join(sourcesService.Providers(), ", "))
+	signatureService := signatures.NewService()
+	cacheService := cache.NewService(parseCacheDir(cacheDirArg), chainId, unverifiedCacheTtl, abiCacheSize)
+	compilerService := compiler.NewService(parseSolcDir(solcDirArg), parseSolcMirror(solcMirrorArg), vyperMirrorArg)
+	localSourcesService := localsources.NewService(parseLocalSourcesDir(localSourcesDirArg))
+	debugService := debug.NewService(forkService, sourcesService, evmService, balanceService, signatureService, cacheService, compilerService, localSourcesService)
+	jobsService := jobs.NewService(debugService, forkService, parseWorkers(simulationWorkersArg))
 
-	ctrl := NewController(forkService, evmService, balanceService, debugService)
+	ctrl := NewController(forkService, evmService, balanceService, debugService, jobsService, cacheService, localSourcesService)
 	e := echo.New()
 
 	e.Use(middleware.CORS())
@@ -59,12 +125,37 @@ func main() {
 	e.POST("/fork/setBalance/:forkId", ctrl.setBalanceHandler)
 	e.POST("/fork/getERC20Balance/:forkId", ctrl.getERC20BalanceHandler)
 	e.POST("/fork/setERC20Balance/:forkId", ctrl.setERC20BalanceHandler)
+	e.POST("/fork/getERC20Allowance/:forkId", ctrl.getERC20AllowanceHandler)
+	e.POST("/fork/setERC20Allowance/:forkId", ctrl.setERC20AllowanceHandler)
+	e.POST("/fork/getTokenMetadata/:forkId", ctrl.getTokenMetadataHandler)
+	e.GET("/fork/:forkId/portfolio/:address", ctrl.getPortfolioHandler)
 
 	e.GET("/debug/getSourceCode", ctrl.getSourceCode)
 	e.GET("/debug/contractsCalled/:forkId", ctrl.getContractsCalledHandler)
 	e.GET("/debug/debugTransaction/:forkId", ctrl.debugTransactionCallTraceHandler)
+	e.GET("/debug/tx/:txHash", ctrl.debugHistoricalTransactionHandler)
+	e.GET("/debug/gasProfile/:forkId", ctrl.gasProfileHandler)
+	e.GET("/debug/verify/:address", ctrl.verifyBytecodeHandler)
+	e.GET("/debug/sources", ctrl.listLocalSourcesHandler)
+	e.POST("/debug/sources/:address", ctrl.registerLocalSourcesHandler)
+	e.DELETE("/debug/sources/:address", ctrl.deleteLocalSourcesHandler)
+	e.POST("/debug/steps/:forkId", ctrl.createStepSessionHandler)
+	e.GET("/debug/stepSessions/:sessionId", ctrl.getStepsHandler)
+	e.GET("/debug/stepSessions/:sessionId/variables", ctrl.getVariablesHandler)
+	e.POST("/debug/stepSessions/:sessionId/navigate", ctrl.navigateHandler)
+	e.DELETE("/debug/stepSessions/:sessionId", ctrl.deleteStepSessionHandler)
 
 	e.POST("/simulate/simulateRawTx", ctrl.simulateRawTxHandler)
+	e.POST("/simulate/call", ctrl.simulateCallHandler)
+	e.POST("/simulate/bundle", ctrl.simulateBundleHandler)
+	e.POST("/simulate", ctrl.submitSimulationHandler)
+	e.GET("/simulate/:jobId/events", ctrl.jobEventsHandler)
+
+	e.GET("/jobs/:jobId", ctrl.getJobHandler)
+	e.DELETE("/jobs/:jobId", ctrl.cancelJobHandler)
+
+	e.GET("/admin/cache", ctrl.getCacheHandler)
+	e.DELETE("/admin/cache", ctrl.purgeCacheHandler)
 
 	// Start the server
 	e.Logger.Fatal(e.Start(":8080"))
@@ -84,3 +175,131 @@ func parsePorts(portsArg string) []int {
 
 	return ports
 }
+
+// Number of simulation jobs running at once, each one uses up to two forks
+func parseWorkers(workersArg string) int {
+	if workersArg == "" {
+		return 2
+	}
+
+	workers, err := strconv.Atoi(workersArg)
+	if err != nil || workers <= 0 {
+		panic("Bad simulation workers environment variable!")
+	}
+
+	return workers
+}
+
+// Chain the source providers are queried for, HyperEVM unless configured
+func parseChainId(chainIdArg string) string {
+	if chainIdArg == "" {
+		return "999"
+	}
+
+	_, err := strconv.ParseUint(chainIdArg, 10, 64)
+	if err != nil {
+		panic("Bad chain ID environment variable!")
+	}
+
+	return chainIdArg
+}
+
+// Order in which the source providers are asked for the configured chain
+func parseSourceProviders(sourceProvidersArg string) []string {
+	if sourceProvidersArg == "" {
+		sourceProvidersArg = defaultSourceProviders
+	}
+
+	var providers []string
+	for _, provider := range strings.Split(sourceProvidersArg, ",") {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if provider != "" {
+			providers = append(providers, provider)
+		}
+	}
+
+	return providers
+}
+
+// Requests per second by provider as name:rate pairs, e.g. etherscan:5,sourcify:10. 0 disables the limit.
+func parseSourceRateLimits(sourceRateLimitsArg string) map[string]float64 {
+	rateLimits := make(map[string]float64)
+	for provider, rateLimit := range defaultSourceRateLimits {
+		rateLimits[provider] = rateLimit
+	}
+
+	for _, entry := range strings.Split(sourceRateLimitsArg, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		provider, rate, found := strings.Cut(entry, ":")
+		rateLimit, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if !found || err != nil || rateLimit < 0 {
+			panic("Bad source rate limits environment variable!")
+		}
+
+		rateLimits[strings.ToLower(strings.TrimSpace(provider))] = rateLimit
+	}
+
+	return rateLimits
+}
+
+// Sourcify server, the public one unless configured
+func parseSourcifyUrl(sourcifyUrlArg string) string {
+	if sourcifyUrlArg == "" {
+		return "https://sourcify.dev/server"
+	}
+
+	return sourcifyUrlArg
+}
+
+func parseCacheDir(cacheDirArg string) string {
+	if cacheDirArg == "" {
+		return "output/cache"
+	}
+
+	return cacheDirArg
+}
+
+// Directory of the sources registered for addresses the explorer has no verified source for
+func parseLocalSourcesDir(localSourcesDirArg string) string {
+	if localSourcesDirArg == "" {
+		return "output/sources"
+	}
+
+	return localSourcesDirArg
+}
+
+// Directory of the solc and vyper binaries, missing ones are installed there from the mirrors
+func parseSolcDir(solcDirArg string) string {
+	if solcDirArg == "" {
+		return "solc"
+	}
+
+	return solcDirArg
+}
+
+// Local directory or URL with the solc binaries and their list.json, the official archive unless configured
+func parseSolcMirror(solcMirrorArg string) string {
+	if solcMirrorArg == "" {
+		return "https://binaries.soliditylang.org/linux-amd64"
+	}
+
+	return solcMirrorArg
+}
+
+func parseAddresses(addressesArg string) []string {
+	var addresses []string
+
+	for _, address := range strings.Split(addressesArg, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}
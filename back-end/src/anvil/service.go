@@ -19,7 +19,8 @@ func NewService() *Service {
 }
 
 func (s *Service) StartAnvilProcess(port int, rpcUrl string, blockNumber string) error {
-	args := []string{"--steps-tracing", "--port", fmt.Sprint(port), "--host", "0.0.0.0", "--fork-url", rpcUrl}
+	// Pending transactions are mined in the order they were sent, so replayed blocks keep their order
+	args := []string{"--steps-tracing", "--order", "fifo", "--port", fmt.Sprint(port), "--host", "0.0.0.0", "--fork-url", rpcUrl}
 	
 	// Add block number flag if specified
 	if blockNumber != "" {
@@ -1,28 +1,46 @@
 package balance
 
 import (
+	evm "Simulations/src/rpc"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/sha3"
 )
 
 type evmService interface {
+	GetBalance(forkId, userAddress string) (int, string, error)
 	GetCurrentSnapshot(forkId string) (string, error)
 	ChangeStorageSlot(forkId, tokenAddress, value, slot string) error
 	RevertState(forkId, snapshot string) error
 	SendCallTransaction(forkId, tokenAddress, funcEncoded string) (string, error)
+	GetLogs(forkId, fromBlock, toBlock string, topics []interface{}) ([]evm.Log, error)
+	GetForkBlockNumber(forkId string) (uint64, error)
 }
 
+// keccak256("Transfer(address,address,uint256)")
+const transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+const (
+	nativeSymbol   = "HYPE"
+	nativeDecimals = 18
+)
+
 type Service struct {
-	evmService evmService
+	evmService      evmService
+	portfolioTokens []string
 }
 
-func NewService(evmService evmService) *Service {
+func NewService(evmService evmService, portfolioTokens []string) *Service {
 	return &Service{
-		evmService: evmService,
+		evmService:      evmService,
+		portfolioTokens: portfolioTokens,
 	}
 }
 
@@ -72,6 +90,223 @@ func (s *Service) GetERC20Balance(forkId, tokenAddress, userAddress string) (str
 	return balance, nil
 }
 
+func (s *Service) SetERC20Allowance(forkId, ownerAddress, spenderAddress, tokenAddress, allowance string) error {
+	wantedAllowance, err := parseHexNumber(allowance)
+	if err != nil {
+		return err
+	}
+
+	// Storage values are full 32 byte words
+	allowanceWord := fmt.Sprintf("0x%064x", wantedAllowance)
+
+	for slotNumber := 0; slotNumber < 100; slotNumber++ {
+		snapshot, err := s.evmService.GetCurrentSnapshot(forkId)
+		if err != nil {
+			return err
+		}
+
+		allowanceSlot, err := getNestedSlot(ownerAddress, spenderAddress, slotNumber)
+		if err != nil {
+			return err
+		}
+
+		err = s.evmService.ChangeStorageSlot(forkId, tokenAddress, allowanceWord, allowanceSlot)
+		if err != nil {
+			return err
+		}
+
+		currAllowance, err := s.GetERC20Allowance(forkId, tokenAddress, ownerAddress, spenderAddress)
+		if err != nil {
+			return err
+		}
+
+		parsedAllowance, err := parseHexNumber(currAllowance)
+		if err == nil && parsedAllowance.Cmp(wantedAllowance) == 0 {
+			return nil
+		}
+
+		errRevert := s.evmService.RevertState(forkId, snapshot)
+		if errRevert != nil {
+			return errRevert
+		}
+	}
+
+	return fmt.Errorf("Mapping slot for given owner and spender not found!")
+}
+
+func (s *Service) GetERC20Allowance(forkId, tokenAddress, ownerAddress, spenderAddress string) (string, error) {
+	funcEncoded := encodeFunctionCall("allowance(address,address)", ownerAddress, spenderAddress)
+
+	allowance, err := s.evmService.SendCallTransaction(forkId, tokenAddress, funcEncoded)
+	if err != nil {
+		return "", err
+	}
+
+	return allowance, nil
+}
+
+func (s *Service) GetTokenMetadata(forkId, tokenAddress string) (TokenMetadata, error) {
+	// Only balanceOf is required to hold a balance, the other properties are left out when a token lacks them
+	metadata := TokenMetadata{
+		Address: tokenAddress,
+		Name:    s.getStringProperty(forkId, tokenAddress, "name()"),
+		Symbol:  s.getStringProperty(forkId, tokenAddress, "symbol()"),
+	}
+
+	decimals := s.getNumberProperty(forkId, tokenAddress, "decimals()")
+	if decimals != nil && decimals.IsUint64() && decimals.Uint64() <= math.MaxUint8 {
+		tokenDecimals := uint8(decimals.Uint64())
+		metadata.Decimals = &tokenDecimals
+	}
+
+	if totalSupply := s.getNumberProperty(forkId, tokenAddress, "totalSupply()"); totalSupply != nil {
+		metadata.TotalSupply = totalSupply.String()
+	}
+
+	return metadata, nil
+}
+
+// Returns the raw balance, formatted as well when the token has decimals
+func (s *Service) GetFormattedERC20Balance(forkId, tokenAddress, userAddress string) (ERC20Balance, error) {
+	balanceRes, err := s.GetERC20Balance(forkId, tokenAddress, userAddress)
+	if err != nil {
+		return ERC20Balance{}, err
+	}
+
+	balance, err := parseHexNumber(balanceRes)
+	if err != nil {
+		return ERC20Balance{}, err
+	}
+
+	metadata, err := s.GetTokenMetadata(forkId, tokenAddress)
+	if err != nil {
+		return ERC20Balance{}, err
+	}
+
+	erc20Balance := ERC20Balance{
+		TokenAddress: tokenAddress,
+		Symbol:       metadata.Symbol,
+		Decimals:     metadata.Decimals,
+		Balance:      balance.String(),
+	}
+
+	if metadata.Decimals != nil {
+		erc20Balance.FormattedBalance = FormatUnits(balance, *metadata.Decimals)
+	}
+
+	return erc20Balance, nil
+}
+
+func (s *Service) GetPortfolio(forkId, userAddress string) (Portfolio, error) {
+	_, nativeBalanceHex, err := s.evmService.GetBalance(forkId, userAddress)
+	if err != nil {
+		return Portfolio{}, err
+	}
+
+	nativeBalance, err := parseHexNumber(nativeBalanceHex)
+	if err != nil {
+		return Portfolio{}, err
+	}
+
+	receivedTokens, err := s.getReceivedTokens(forkId, userAddress)
+	if err != nil {
+		return Portfolio{}, err
+	}
+
+	portfolio := Portfolio{
+		Address: userAddress,
+		Native: NativeBalance{
+			Symbol:           nativeSymbol,
+			Decimals:         nativeDecimals,
+			Balance:          nativeBalance.String(),
+			FormattedBalance: FormatUnits(nativeBalance, nativeDecimals),
+		},
+		Tokens: []ERC20Balance{},
+	}
+
+	tokenAddresses := append([]string{}, s.portfolioTokens...)
+	tokenAddresses = append(tokenAddresses, receivedTokens...)
+
+	seenTokens := make(map[string]bool)
+	for _, tokenAddress := range tokenAddresses {
+		tokenKey := strings.ToLower(tokenAddress)
+		if seenTokens[tokenKey] {
+			continue
+		}
+		seenTokens[tokenKey] = true
+
+		tokenBalance, err := s.GetFormattedERC20Balance(forkId, tokenAddress, userAddress)
+		if err != nil {
+			log.Warnf("Skipping token %v in portfolio: %v", tokenAddress, err)
+			continue
+		}
+
+		if tokenBalance.Balance == "0" {
+			continue
+		}
+
+		portfolio.Tokens = append(portfolio.Tokens, tokenBalance)
+	}
+
+	return portfolio, nil
+}
+
+// Tokens transferred to userAddress since the fork was created
+func (s *Service) getReceivedTokens(forkId, userAddress string) ([]string, error) {
+	forkBlockNumber, err := s.evmService.GetForkBlockNumber(forkId)
+	if err != nil {
+		return nil, err
+	}
+
+	recipientTopic := "0x" + hex.EncodeToString(common.LeftPadBytes(common.HexToAddress(userAddress).Bytes(), 32))
+	topics := []interface{}{transferTopic, nil, recipientTopic}
+
+	logs, err := s.evmService.GetLogs(forkId, fmt.Sprintf("0x%x", forkBlockNumber+1), "latest", topics)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []string
+	for _, transferLog := range logs {
+		// ERC721 transfers share the topic but index the token id as well
+		if len(transferLog.Topics) != 3 {
+			continue
+		}
+
+		tokens = append(tokens, transferLog.Address)
+	}
+
+	return tokens, nil
+}
+
+func (s *Service) getStringProperty(forkId, tokenAddress, funcSignature string) string {
+	res, err := s.evmService.SendCallTransaction(forkId, tokenAddress, encodeFunctionCall(funcSignature))
+	if err != nil {
+		return ""
+	}
+
+	value, err := decodeString(res)
+	if err != nil {
+		return ""
+	}
+
+	return value
+}
+
+func (s *Service) getNumberProperty(forkId, tokenAddress, funcSignature string) *big.Int {
+	res, err := s.evmService.SendCallTransaction(forkId, tokenAddress, encodeFunctionCall(funcSignature))
+	if err != nil {
+		return nil
+	}
+
+	value, err := parseHexNumber(res)
+	if err != nil {
+		return nil
+	}
+
+	return value
+}
+
 func getSlot(account string, slotNumber int) (string, error) {
 	// Trim the 0x from account
 	account = strings.TrimPrefix(account, "0x")
@@ -95,6 +330,110 @@ func getSlot(account string, slotNumber int) (string, error) {
 	return "0x" + hex.EncodeToString(hashed), nil
 }
 
+// Slot of mapping[outerKey][innerKey] where the mapping is declared at slotNumber
+func getNestedSlot(outerKey, innerKey string, slotNumber int) (string, error) {
+	outerKey = strings.TrimPrefix(outerKey, "0x")
+	innerKey = strings.TrimPrefix(innerKey, "0x")
+
+	outerData, err := hex.DecodeString(fmt.Sprintf("%064s", outerKey) + fmt.Sprintf("%064x", slotNumber))
+	if err != nil {
+		return "", err
+	}
+
+	innerData, err := hex.DecodeString(fmt.Sprintf("%064s", innerKey))
+	if err != nil {
+		return "", err
+	}
+
+	hashFunc := sha3.NewLegacyKeccak256()
+	hashFunc.Write(outerData)
+	outerSlot := hashFunc.Sum(nil)
+
+	hashFunc.Reset()
+	hashFunc.Write(append(innerData, outerSlot...))
+	hashed := hashFunc.Sum(nil)
+
+	return "0x" + hex.EncodeToString(hashed), nil
+}
+
+// Encodes a call to funcSignature with address arguments only
+func encodeFunctionCall(funcSignature string, addresses ...string) string {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(funcSignature))
+	encodedData := hash.Sum(nil)[:4]
+
+	for _, address := range addresses {
+		encodedData = append(encodedData, common.LeftPadBytes(common.HexToAddress(address).Bytes(), 32)...)
+	}
+
+	return hex.EncodeToString(encodedData)
+}
+
+func parseHexNumber(hexNumber string) (*big.Int, error) {
+	trimmed := strings.TrimPrefix(hexNumber, "0x")
+	if trimmed == "" {
+		return nil, errors.New("empty call result")
+	}
+
+	number, success := new(big.Int).SetString(trimmed, 16)
+	if !success {
+		return nil, fmt.Errorf("invalid hex number: %s", hexNumber)
+	}
+
+	return number, nil
+}
+
+// Decodes an ABI encoded string, falling back to bytes32 for tokens like MKR
+func decodeString(encoded string) (string, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) == 32 {
+		return strings.TrimRight(string(data), "\x00"), nil
+	}
+
+	if len(data) < 64 {
+		return "", errors.New("result too short to be a string")
+	}
+
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64()+32 > uint64(len(data)) {
+		return "", errors.New("invalid string offset")
+	}
+
+	start := offset.Uint64() + 32
+	length := new(big.Int).SetBytes(data[offset.Uint64():start])
+	if !length.IsUint64() || start+length.Uint64() > uint64(len(data)) {
+		return "", errors.New("invalid string length")
+	}
+
+	return string(data[start : start+length.Uint64()]), nil
+}
+
+// FormatUnits formats a raw token amount with the given number of decimals, e.g. 1500000 with 6 decimals is 1.5
+func FormatUnits(amount *big.Int, decimals uint8) string {
+	if decimals == 0 {
+		return amount.String()
+	}
+
+	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	whole, fraction := new(big.Int).QuoRem(new(big.Int).Abs(amount), divisor, new(big.Int))
+
+	formatted := whole.String()
+	fractionStr := strings.TrimRight(fmt.Sprintf("%0*s", int(decimals), fraction.String()), "0")
+	if fractionStr != "" {
+		formatted += "." + fractionStr
+	}
+
+	if amount.Sign() < 0 {
+		formatted = "-" + formatted
+	}
+
+	return formatted
+}
+
 func encodeBalanceOf(userAddress string) string {
 	funcSignature := "balanceOf(address)"
 
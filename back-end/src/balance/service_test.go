@@ -0,0 +1,35 @@
+package balance
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		decimals uint8
+		expected string
+	}{
+		{name: "fraction", amount: "1500000", decimals: 6, expected: "1.5"},
+		{name: "whole amount", amount: "1000000", decimals: 6, expected: "1"},
+		{name: "zero", amount: "0", decimals: 18, expected: "0"},
+		{name: "smallest unit", amount: "1", decimals: 18, expected: "0.000000000000000001"},
+		{name: "no decimals", amount: "123", decimals: 0, expected: "123"},
+		{name: "negative", amount: "-1500000", decimals: 6, expected: "-1.5"},
+		{name: "negative below one", amount: "-5", decimals: 2, expected: "-0.05"},
+		{name: "large amount", amount: "1000000000000000000000000000001", decimals: 18, expected: "1000000000000.000000000000000001"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			amount, _ := new(big.Int).SetString(test.amount, 10)
+
+			formatted := FormatUnits(amount, test.decimals)
+			if formatted != test.expected {
+				t.Errorf("FormatUnits(%s, %d) = %s, expected %s", test.amount, test.decimals, formatted, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package balance
+
+// ERC20 token metadata as returned by the token contract
+type TokenMetadata struct {
+	Address     string `json:"address"`
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol"`
+	Decimals    *uint8 `json:"decimals,omitempty"`
+	TotalSupply string `json:"totalSupply,omitempty"`
+}
+
+// ERC20 balance with decimals applied, tokens without decimals only have the raw balance
+type ERC20Balance struct {
+	TokenAddress     string `json:"tokenAddress"`
+	Symbol           string `json:"symbol"`
+	Decimals         *uint8 `json:"decimals,omitempty"`
+	Balance          string `json:"balance"`
+	FormattedBalance string `json:"formattedBalance,omitempty"`
+}
+
+// Native balance of an address
+type NativeBalance struct {
+	Symbol           string `json:"symbol"`
+	Decimals         uint8  `json:"decimals"`
+	Balance          string `json:"balance"`
+	FormattedBalance string `json:"formattedBalance"`
+}
+
+// All known balances of an address on a fork
+type Portfolio struct {
+	Address string         `json:"address"`
+	Native  NativeBalance  `json:"native"`
+	Tokens  []ERC20Balance `json:"tokens"`
+}
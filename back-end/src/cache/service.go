@@ -0,0 +1,290 @@
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Marker file of contracts the explorer has no verified source for
+const unverifiedFile = "unverified"
+
+// Service stores explorer sources and compiled artifacts on disk under root/chainId/address/codeHash,
+// so a redeployed or upgraded address never reuses the artifacts of other code. ABIs are kept in memory.
+type Service struct {
+	root          string
+	chainId       string
+	unverifiedTtl time.Duration
+	abiCacheSize  int
+	abis          map[string]*list.Element
+	abiOrder      *list.List // Most recently used first
+	abiMutex      sync.Mutex
+}
+
+func NewService(root string, chainId string, unverifiedTtl time.Duration, abiCacheSize int) *Service {
+	return &Service{
+		root:          root,
+		chainId:       chainId,
+		unverifiedTtl: unverifiedTtl,
+		abiCacheSize:  abiCacheSize,
+		abis:          make(map[string]*list.Element),
+		abiOrder:      list.New(),
+	}
+}
+
+// Read returns a cached file of a contract deployment
+func (s *Service) Read(address string, codeHash string, name string) ([]byte, bool) {
+	if !isValidKey(address, codeHash) {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.entryDir(address, codeHash), name))
+	if err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+// Write stores a file atomically, readers see either the previous content or the complete new one
+func (s *Service) Write(address string, codeHash string, name string, data []byte) error {
+	if !isValidKey(address, codeHash) {
+		return errors.New("invalid cache key")
+	}
+
+	dir := s.entryDir(address, codeHash)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(data)
+	if err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	err = tempFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), filepath.Join(dir, name))
+}
+
+// MarkUnverified remembers that a contract has no verified source, until the TTL expires
+func (s *Service) MarkUnverified(address string, codeHash string) error {
+	return s.Write(address, codeHash, unverifiedFile, []byte(time.Now().UTC().Format(time.RFC3339)))
+}
+
+func (s *Service) IsUnverified(address string, codeHash string) bool {
+	if !isValidKey(address, codeHash) {
+		return false
+	}
+
+	path := filepath.Join(s.entryDir(address, codeHash), unverifiedFile)
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	// Contracts can get verified later, expired markers are dropped so the explorer is asked again
+	if time.Since(fileInfo.ModTime()) > s.unverifiedTtl {
+		os.Remove(path)
+		return false
+	}
+
+	return true
+}
+
+// GetAbi returns a cached ABI, verified is false when the explorer recently had no ABI for the address
+func (s *Service) GetAbi(address string) (abi string, verified bool, found bool) {
+	s.abiMutex.Lock()
+	defer s.abiMutex.Unlock()
+
+	element, exists := s.abis[strings.ToLower(address)]
+	if !exists {
+		return "", false, false
+	}
+
+	entry := element.Value.(*abiEntry)
+	if entry.abi == "" && time.Since(entry.fetchedAt) > s.unverifiedTtl {
+		s.abiOrder.Remove(element)
+		delete(s.abis, entry.address)
+		return "", false, false
+	}
+
+	s.abiOrder.MoveToFront(element)
+	return entry.abi, entry.abi != "", true
+}
+
+// PutAbi caches an ABI, an empty one for a contract that isn't verified. The least recently used ABI is evicted when full.
+func (s *Service) PutAbi(address string, abi string) {
+	s.abiMutex.Lock()
+	defer s.abiMutex.Unlock()
+
+	address = strings.ToLower(address)
+	entry := &abiEntry{address: address, abi: abi, fetchedAt: time.Now()}
+
+	if element, exists := s.abis[address]; exists {
+		element.Value = entry
+		s.abiOrder.MoveToFront(element)
+		return
+	}
+
+	s.abis[address] = s.abiOrder.PushFront(entry)
+
+	for s.abiOrder.Len() > s.abiCacheSize {
+		oldest := s.abiOrder.Back()
+		s.abiOrder.Remove(oldest)
+		delete(s.abis, oldest.Value.(*abiEntry).address)
+	}
+}
+
+// List returns the cached deployments of the chain
+func (s *Service) List() (Summary, error) {
+	summary := Summary{Root: s.root, Entries: []Entry{}}
+
+	chainDir := filepath.Join(s.root, s.chainId)
+	addresses, err := os.ReadDir(chainDir)
+	if err != nil && !os.IsNotExist(err) {
+		return Summary{}, err
+	}
+
+	for _, address := range addresses {
+		if !address.IsDir() {
+			continue
+		}
+
+		codeHashes, err := os.ReadDir(filepath.Join(chainDir, address.Name()))
+		if err != nil {
+			return Summary{}, err
+		}
+
+		for _, codeHash := range codeHashes {
+			if !codeHash.IsDir() {
+				continue
+			}
+
+			entry, err := s.readEntry(address.Name(), codeHash.Name())
+			if err != nil {
+				return Summary{}, err
+			}
+			summary.Entries = append(summary.Entries, entry)
+		}
+	}
+
+	s.abiMutex.Lock()
+	summary.AbiEntries = s.abiOrder.Len()
+	s.abiMutex.Unlock()
+
+	return summary, nil
+}
+
+// Purge removes the cached artifacts and ABI of an address, of one of its deployments when a code hash
+// is given, or everything cached for the chain when the address is empty. Returns the number of entries removed.
+func (s *Service) Purge(address string, codeHash string) (int, error) {
+	if address == "" && codeHash != "" {
+		return 0, errors.New("code hash requires an address")
+	}
+
+	summary, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range summary.Entries {
+		if address != "" && entry.Address != strings.ToLower(address) {
+			continue
+		}
+		if codeHash != "" && entry.CodeHash != strings.ToLower(codeHash) {
+			continue
+		}
+
+		err := os.RemoveAll(s.entryDir(entry.Address, entry.CodeHash))
+		if err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	s.abiMutex.Lock()
+	defer s.abiMutex.Unlock()
+
+	if address == "" {
+		s.abis = make(map[string]*list.Element)
+		s.abiOrder.Init()
+	} else if element, exists := s.abis[strings.ToLower(address)]; exists {
+		s.abiOrder.Remove(element)
+		delete(s.abis, strings.ToLower(address))
+	}
+
+	return removed, nil
+}
+
+func (s *Service) readEntry(address string, codeHash string) (Entry, error) {
+	entry := Entry{ChainId: s.chainId, Address: address, CodeHash: codeHash, Files: []string{}}
+
+	files, err := os.ReadDir(s.entryDir(address, codeHash))
+	if err != nil {
+		return Entry{}, err
+	}
+
+	for _, file := range files {
+		// Skip writes in progress
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		fileInfo, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		if file.Name() == unverifiedFile {
+			entry.Unverified = time.Since(fileInfo.ModTime()) <= s.unverifiedTtl
+		} else {
+			entry.Files = append(entry.Files, file.Name())
+		}
+
+		entry.Size += fileInfo.Size()
+		if fileInfo.ModTime().After(entry.ModifiedAt) {
+			entry.ModifiedAt = fileInfo.ModTime()
+		}
+	}
+
+	return entry, nil
+}
+
+func (s *Service) entryDir(address string, codeHash string) string {
+	return filepath.Join(s.root, s.chainId, strings.ToLower(address), strings.ToLower(codeHash))
+}
+
+// Keys are hex strings, which keeps them inside the cache directory
+func isValidKey(address string, codeHash string) bool {
+	for _, key := range []string{address, codeHash} {
+		if !strings.HasPrefix(key, "0x") || len(key) < 3 {
+			return false
+		}
+
+		for _, char := range strings.ToLower(key[2:]) {
+			if !strings.ContainsRune("0123456789abcdef", char) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+const (
+	addressA = "0x000000000000000000000000000000000000000a"
+	addressB = "0x000000000000000000000000000000000000000b"
+	hash1    = "0x1111111111111111111111111111111111111111111111111111111111111111"
+	hash2    = "0x2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func TestAbiCacheEviction(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		operations []string // "put:<address>" or "get:<address>"
+		expected   []string // Cached addresses afterwards
+	}{
+		{
+			name:       "within capacity",
+			size:       3,
+			operations: []string{"put:0x1", "put:0x2", "put:0x3"},
+			expected:   []string{"0x1", "0x2", "0x3"},
+		},
+		{
+			name:       "oldest is evicted",
+			size:       2,
+			operations: []string{"put:0x1", "put:0x2", "put:0x3"},
+			expected:   []string{"0x2", "0x3"},
+		},
+		{
+			name:       "reading keeps an entry",
+			size:       2,
+			operations: []string{"put:0x1", "put:0x2", "get:0x1", "put:0x3"},
+			expected:   []string{"0x1", "0x3"},
+		},
+		{
+			name:       "updating keeps an entry",
+			size:       2,
+			operations: []string{"put:0x1", "put:0x2", "put:0x1", "put:0x3"},
+			expected:   []string{"0x1", "0x3"},
+		},
+		{
+			name:       "addresses are case insensitive",
+			size:       2,
+			operations: []string{"put:0xAB", "put:0xab", "put:0x2"},
+			expected:   []string{"0x2", "0xab"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewService(t.TempDir(), "1", time.Hour, test.size)
+
+			for _, operation := range test.operations {
+				address := operation[4:]
+				if operation[:4] == "put:" {
+					s.PutAbi(address, "[]")
+				} else {
+					s.GetAbi(address)
+				}
+			}
+
+			var cached []string
+			for address := range s.abis {
+				cached = append(cached, address)
+			}
+			sort.Strings(cached)
+
+			if !reflect.DeepEqual(cached, test.expected) {
+				t.Errorf("cached %v, expected %v", cached, test.expected)
+			}
+		})
+	}
+}
+
+func TestUnverifiedExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		age      time.Duration
+		expected bool
+	}{
+		{name: "fresh marker", age: time.Minute, expected: true},
+		{name: "expired marker", age: 2 * time.Hour, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewService(t.TempDir(), "1", time.Hour, 10)
+
+			err := s.MarkUnverified(addressA, hash1)
+			if err != nil {
+				t.Fatalf("MarkUnverified failed: %v", err)
+			}
+
+			marked := time.Now().Add(-test.age)
+			err = os.Chtimes(filepath.Join(s.entryDir(addressA, hash1), unverifiedFile), marked, marked)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if unverified := s.IsUnverified(addressA, hash1); unverified != test.expected {
+				t.Errorf("IsUnverified = %v, expected %v", unverified, test.expected)
+			}
+
+			// Other code at the address isn't affected by the marker
+			if s.IsUnverified(addressA, hash2) {
+				t.Error("marker of one code hash applies to another")
+			}
+
+			s.PutAbi(addressA, "")
+			s.abis[addressA].Value.(*abiEntry).fetchedAt = marked
+
+			_, verified, found := s.GetAbi(addressA)
+			if found != test.expected || verified {
+				t.Errorf("GetAbi of a missing ABI found %v verified %v, expected found %v", found, verified, test.expected)
+			}
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	tests := []struct {
+		name            string
+		address         string
+		codeHash        string
+		expectedRemoved int
+		expectedLeft    []string // address/codeHash of the remaining entries
+		expectedAbis    int
+		expectErr       bool
+	}{
+		{
+			name:            "one deployment",
+			address:         addressA,
+			codeHash:        hash1,
+			expectedRemoved: 1,
+			expectedLeft:    []string{addressA + "/" + hash2, addressB + "/" + hash1},
+			expectedAbis:    1,
+		},
+		{
+			name:            "every deployment of an address",
+			address:         "0x000000000000000000000000000000000000000A",
+			expectedRemoved: 2,
+			expectedLeft:    []string{addressB + "/" + hash1},
+			expectedAbis:    1,
+		},
+		{
+			name:            "whole chain",
+			expectedRemoved: 3,
+			expectedAbis:    0,
+		},
+		{
+			name:         "code hash without address",
+			codeHash:     hash1,
+			expectedLeft: []string{addressA + "/" + hash1, addressA + "/" + hash2, addressB + "/" + hash1},
+			expectedAbis: 2,
+			expectErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewService(t.TempDir(), "1", time.Hour, 10)
+
+			for _, key := range [][2]string{{addressA, hash1}, {addressA, hash2}, {addressB, hash1}} {
+				err := s.Write(key[0], key[1], "metadata.json", []byte("{}"))
+				if err != nil {
+					t.Fatalf("Write failed: %v", err)
+				}
+			}
+			s.PutAbi(addressA, "[]")
+			s.PutAbi(addressB, "[]")
+
+			removed, err := s.Purge(test.address, test.codeHash)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("Purge error %v, expected error %v", err, test.expectErr)
+			}
+			if removed != test.expectedRemoved {
+				t.Errorf("removed %d entries, expected %d", removed, test.expectedRemoved)
+			}
+
+			summary, err := s.List()
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+
+			var left []string
+			for _, entry := range summary.Entries {
+				left = append(left, entry.Address+"/"+entry.CodeHash)
+			}
+			sort.Strings(left)
+
+			if len(left)+len(test.expectedLeft) > 0 && !reflect.DeepEqual(left, test.expectedLeft) {
+				t.Errorf("entries left %v, expected %v", left, test.expectedLeft)
+			}
+			if summary.AbiEntries != test.expectedAbis {
+				t.Errorf("%d ABIs left, expected %d", summary.AbiEntries, test.expectedAbis)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package cache
+
+import "time"
+
+// Cached artifacts of one contract deployment
+type Entry struct {
+	ChainId    string    `json:"chainId"`
+	Address    string    `json:"address"`
+	CodeHash   string    `json:"codeHash"`
+	Files      []string  `json:"files"`
+	Unverified bool      `json:"unverified"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// Contents of the cache as reported by the admin endpoint
+type Summary struct {
+	Root       string  `json:"root"`
+	Entries    []Entry `json:"entries"`
+	AbiEntries int     `json:"abiEntries"`
+}
+
+// ABI kept in memory, an empty ABI records that the contract isn't verified
+type abiEntry struct {
+	address   string
+	abi       string
+	fetchedAt time.Time
+}
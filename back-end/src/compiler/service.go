@@ -0,0 +1,212 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Explorer compiler versions, e.g. v0.8.24+commit.e11b9ed9 and vyper:0.3.10
+var (
+	solcVersionPattern  = regexp.MustCompile(`^v?(\d+\.\d+\.\d+(?:-nightly\.\d{4}\.\d{1,2}\.\d{1,2})?\+commit\.[0-9a-f]{8})$`)
+	vyperVersionPattern = regexp.MustCompile(`^vyper:(\d+\.\d+\.\d+(?:(?:b|rc)\d+)?)(?:\+commit\.[0-9a-f]+)?$`)
+)
+
+// Service finds compiler binaries in binDir and installs missing ones from mirrors laid out like
+// binaries.soliditylang.org, each either a local directory or an HTTP URL
+type Service struct {
+	binDir       string
+	mirrors      map[string]string // Mirror of every compiler
+	buildLists   map[string]*BuildList
+	installMutex sync.Mutex
+}
+
+func NewService(binDir string, solcMirror string, vyperMirror string) *Service {
+	return &Service{
+		binDir: binDir,
+		mirrors: map[string]string{
+			"solc":  strings.TrimSuffix(solcMirror, "/"),
+			"vyper": strings.TrimSuffix(vyperMirror, "/"),
+		},
+		buildLists: make(map[string]*BuildList),
+	}
+}
+
+// GetSolc returns the path of the solc binary matching an explorer compiler version, installing it when missing
+func (s *Service) GetSolc(compilerVersion string) (string, error) {
+	match := solcVersionPattern.FindStringSubmatch(compilerVersion)
+	if match == nil {
+		return "", &MissingCompilerError{Compiler: "solc", Version: compilerVersion, Reason: "unrecognized compiler version"}
+	}
+	longVersion := match[1]
+
+	// Binaries are named after the explorer version, as they used to be installed by hand
+	binPath := filepath.Join(s.binDir, "v"+longVersion)
+
+	err := s.getCompiler("solc", binPath, func(build Build) bool { return build.LongVersion == longVersion })
+	if err != nil {
+		return "", &MissingCompilerError{Compiler: "solc", Version: compilerVersion, Reason: err.Error()}
+	}
+
+	return binPath, nil
+}
+
+// GetVyper returns the path of the vyper binary matching an explorer compiler version, installing it when missing
+func (s *Service) GetVyper(compilerVersion string) (string, error) {
+	match := vyperVersionPattern.FindStringSubmatch(compilerVersion)
+	if match == nil {
+		return "", &MissingCompilerError{Compiler: "vyper", Version: compilerVersion, Reason: "unrecognized compiler version"}
+	}
+	version := match[1]
+
+	binPath := filepath.Join(s.binDir, "vyper-"+version)
+
+	err := s.getCompiler("vyper", binPath, func(build Build) bool { return build.Version == version })
+	if err != nil {
+		return "", &MissingCompilerError{Compiler: "vyper", Version: compilerVersion, Reason: err.Error()}
+	}
+
+	return binPath, nil
+}
+
+// Makes sure binPath exists, installing the first build of the compiler's mirror that matches
+func (s *Service) getCompiler(compiler string, binPath string, matches func(build Build) bool) error {
+	if isExecutable(binPath) {
+		return nil
+	}
+
+	s.installMutex.Lock()
+	defer s.installMutex.Unlock()
+
+	// Installed while waiting for the lock
+	if isExecutable(binPath) {
+		return nil
+	}
+
+	mirror := s.mirrors[compiler]
+	if mirror == "" {
+		return fmt.Errorf("not found in %s and no mirror is configured", s.binDir)
+	}
+
+	buildList, err := s.getBuildList(compiler)
+	if err != nil {
+		return err
+	}
+
+	var build *Build
+	for i := range buildList.Builds {
+		if matches(buildList.Builds[i]) {
+			build = &buildList.Builds[i]
+			break
+		}
+	}
+
+	if build == nil {
+		// Read the list again next time in case the release is newer than the list
+		delete(s.buildLists, compiler)
+		return fmt.Errorf("no build in %s", mirror)
+	}
+
+	fmt.Printf("⬇️  Installing %s from %s\n", build.Path, mirror)
+	binary, err := readMirrorFile(mirror, build.Path)
+	if err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256(binary)
+	if !strings.EqualFold(hex.EncodeToString(checksum[:]), strings.TrimPrefix(build.Sha256, "0x")) {
+		return fmt.Errorf("checksum mismatch for %s", build.Path)
+	}
+
+	err = writeExecutable(binPath, binary)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Installed %s\n", binPath)
+	return nil
+}
+
+// Must be called with the install mutex held, each list is read once
+func (s *Service) getBuildList(compiler string) (*BuildList, error) {
+	if buildList, exists := s.buildLists[compiler]; exists {
+		return buildList, nil
+	}
+
+	rawData, err := readMirrorFile(s.mirrors[compiler], "list.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var buildList BuildList
+	err = json.Unmarshal(rawData, &buildList)
+	if err != nil {
+		return nil, err
+	}
+
+	s.buildLists[compiler] = &buildList
+	return &buildList, nil
+}
+
+func readMirrorFile(mirror string, name string) ([]byte, error) {
+	if !strings.HasPrefix(mirror, "http://") && !strings.HasPrefix(mirror, "https://") {
+		return os.ReadFile(filepath.Join(strings.TrimPrefix(mirror, "file://"), filepath.Base(name)))
+	}
+
+	res, err := http.Get(mirror + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s failed with status %d", name, res.StatusCode)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+// Writes through a temporary file so a failed download never leaves a broken binary behind
+func writeExecutable(binPath string, binary []byte) error {
+	err := os.MkdirAll(filepath.Dir(binPath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(binPath), "."+filepath.Base(binPath)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(binary)
+	if err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	err = tempFile.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tempFile.Name(), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), binPath)
+}
+
+func isExecutable(binPath string) bool {
+	fileInfo, err := os.Stat(binPath)
+	return err == nil && !fileInfo.IsDir() && fileInfo.Mode()&0111 != 0
+}
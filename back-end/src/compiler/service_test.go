@@ -0,0 +1,110 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testVersion = "v0.8.24+commit.e11b9ed9"
+
+func TestGetSolc(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho solc\n")
+	checksum := sha256.Sum256(binary)
+	validChecksum := hex.EncodeToString(checksum[:])
+
+	tests := []struct {
+		name        string
+		version     string
+		checksum    string
+		longVersion string
+		noMirror    bool
+		installed   bool
+		expectedErr string // Empty when the binary should be installed
+	}{
+		{name: "matching checksum", version: testVersion, checksum: validChecksum},
+		{name: "prefixed upper case checksum", version: testVersion, checksum: "0x" + strings.ToUpper(validChecksum)},
+		{name: "checksum mismatch", version: testVersion, checksum: strings.Repeat("0", 64), expectedErr: "checksum mismatch"},
+		{name: "version not in the build list", version: testVersion, checksum: validChecksum, longVersion: "0.8.23+commit.f704f362", expectedErr: "no build"},
+		{name: "no mirror", version: testVersion, noMirror: true, expectedErr: "no mirror is configured"},
+		{name: "already installed", version: testVersion, noMirror: true, installed: true},
+		{name: "unrecognized version", version: "0.8", expectedErr: "unrecognized compiler version"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			binDir := t.TempDir()
+			mirror := t.TempDir()
+
+			longVersion := test.longVersion
+			if longVersion == "" {
+				longVersion = "0.8.24+commit.e11b9ed9"
+			}
+			buildList := BuildList{Builds: []Build{{
+				Path:        "solc-linux-amd64-v" + longVersion,
+				Version:     strings.Split(longVersion, "+")[0],
+				LongVersion: longVersion,
+				Sha256:      test.checksum,
+			}}}
+			writeMirror(t, mirror, buildList, binary)
+
+			if test.noMirror {
+				mirror = ""
+			}
+			if test.installed {
+				err := writeExecutable(filepath.Join(binDir, testVersion), binary)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s := NewService(binDir, mirror, "")
+			binPath, err := s.GetSolc(test.version)
+
+			if test.expectedErr != "" {
+				var missing *MissingCompilerError
+				if !errors.As(err, &missing) || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("error %v, expected a missing compiler error containing %q", err, test.expectedErr)
+				}
+
+				// A rejected download must not leave anything that looks installed
+				entries, _ := os.ReadDir(binDir)
+				if len(entries) != 0 {
+					t.Errorf("%d files left in the binary directory", len(entries))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GetSolc failed: %v", err)
+			}
+			if !isExecutable(binPath) {
+				t.Errorf("%s is not an executable", binPath)
+			}
+		})
+	}
+}
+
+func writeMirror(t *testing.T, mirror string, buildList BuildList, binary []byte) {
+	rawData, err := json.Marshal(buildList)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(mirror, "list.json"), rawData, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, build := range buildList.Builds {
+		err = os.WriteFile(filepath.Join(mirror, build.Path), binary, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
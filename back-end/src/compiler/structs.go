@@ -0,0 +1,24 @@
+package compiler
+
+// Build list published next to the compiler binaries, as on binaries.soliditylang.org
+type BuildList struct {
+	Builds []Build `json:"builds"`
+}
+
+type Build struct {
+	Path        string `json:"path"`
+	Version     string `json:"version"`
+	LongVersion string `json:"longVersion"`
+	Sha256      string `json:"sha256"`
+}
+
+// MissingCompilerError is returned when a compiler is neither installed nor available from the mirror
+type MissingCompilerError struct {
+	Compiler string
+	Version  string
+	Reason   string
+}
+
+func (e *MissingCompilerError) Error() string {
+	return e.Compiler + " " + e.Version + " is not available: " + e.Reason
+}
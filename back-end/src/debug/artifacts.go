@@ -0,0 +1,302 @@
+package debug
+
+import (
+	"Simulations/src/etherscan"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Standard JSON output with the runtime code of every contract and the positions filled at deployment
+type compilerOutput struct {
+	Errors []struct {
+		Severity         string `json:"severity"`
+		FormattedMessage string `json:"formattedMessage"`
+	} `json:"errors"`
+	Contracts map[string]map[string]struct {
+		Evm struct {
+			DeployedBytecode deployedBytecode `json:"deployedBytecode"`
+		} `json:"evm"`
+	} `json:"contracts"`
+	Sources map[string]Source `json:"sources"`
+}
+
+type deployedBytecode struct {
+	Object              string                                  `json:"object"`
+	SourceMap           string                                  `json:"sourceMap"`
+	ImmutableReferences map[string][]immutableOffset            `json:"immutableReferences"`
+	LinkReferences      map[string]map[string][]immutableOffset `json:"linkReferences"`
+}
+
+type immutableOffset struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// Contract picked from a compiler output for a deployment
+type selectedContract struct {
+	fileName   string
+	name       string
+	bytecode   deployedBytecode
+	comparison CodeComparison
+}
+
+// Explorer sources as a standard JSON input, single files get the explorer's compiler settings
+func buildStandardJsonInput(info etherscan.SourceCodeInfo, address string) (map[string]interface{}, error) {
+	input := make(map[string]interface{})
+
+	if info.IsStandardJSON {
+		err := json.Unmarshal([]byte(info.SourceCode), &input)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		runs, _ := strconv.Atoi(info.Runs)
+		settings := map[string]interface{}{
+			"optimizer": map[string]interface{}{"enabled": info.OptimizationUsed == "1", "runs": runs},
+		}
+		if info.EVMVersion != "" && strings.ToLower(info.EVMVersion) != "default" {
+			settings["evmVersion"] = strings.ToLower(info.EVMVersion)
+		}
+
+		input["language"] = "Solidity"
+		input["sources"] = map[string]interface{}{address + ".sol": map[string]interface{}{"content": info.SourceCode}}
+		input["settings"] = settings
+	}
+
+	settings, _ := input["settings"].(map[string]interface{})
+	if settings == nil {
+		settings = make(map[string]interface{})
+		input["settings"] = settings
+	}
+
+	// Libraries the explorer linked, at the global level since the files using them aren't known
+	if _, exists := settings["libraries"]; !exists {
+		if libraries := parseLibraries(info.Library); len(libraries) > 0 {
+			settings["libraries"] = map[string]interface{}{"": libraries}
+		}
+	}
+
+	settings["outputSelection"] = map[string]interface{}{
+		"*": map[string]interface{}{
+			"*": []string{
+				"evm.deployedBytecode.object",
+				"evm.deployedBytecode.sourceMap",
+				"evm.deployedBytecode.immutableReferences",
+				"evm.deployedBytecode.linkReferences",
+			},
+			"": []string{"ast"},
+		},
+	}
+
+	return input, nil
+}
+
+// Explorers list libraries as Name:address pairs separated by semicolons
+func parseLibraries(library string) map[string]string {
+	libraries := make(map[string]string)
+
+	for _, entry := range strings.FieldsFunc(library, func(r rune) bool { return r == ';' || r == ',' }) {
+		name, address, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found {
+			continue
+		}
+
+		address = strings.TrimSpace(address)
+		if !strings.HasPrefix(address, "0x") {
+			address = "0x" + address
+		}
+		libraries[strings.TrimSpace(name)] = address
+	}
+
+	return libraries
+}
+
+func runSolc(solc string, input map[string]interface{}) (compilerOutput, error) {
+	rawInput, err := json.Marshal(input)
+	if err != nil {
+		return compilerOutput{}, err
+	}
+
+	cmd := exec.Command(solc, "--standard-json")
+	cmd.Stdin = bytes.NewReader(rawInput)
+
+	rawOutput, err := cmd.Output()
+	if err != nil {
+		return compilerOutput{}, err
+	}
+
+	var output compilerOutput
+	err = json.Unmarshal(rawOutput, &output)
+	if err != nil {
+		return compilerOutput{}, err
+	}
+
+	var compileErrors []string
+	for _, compileError := range output.Errors {
+		if compileError.Severity == "error" {
+			compileErrors = append(compileErrors, compileError.FormattedMessage)
+		}
+	}
+	if len(compileErrors) > 0 {
+		return compilerOutput{}, errors.New(strings.Join(compileErrors, "\n"))
+	}
+
+	return output, nil
+}
+
+// Picks the contract of the explorer info from the compiler output. Contracts sharing the name are ranked
+// by how well their file matches the explorer's, and the first one whose code matches deployedCode wins.
+func selectContract(output compilerOutput, info etherscan.SourceCodeInfo, deployedCode string) (selectedContract, error) {
+	fileHint, name := splitContractName(info)
+
+	var candidates []selectedContract
+	for fileName, contracts := range output.Contracts {
+		if contract, exists := contracts[name]; exists {
+			candidates = append(candidates, selectedContract{fileName: fileName, name: name, bytecode: contract.Evm.DeployedBytecode})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return selectedContract{}, fmt.Errorf("contract %s not found in compiler output", info.ContractName)
+	}
+
+	rank := func(fileName string) int {
+		switch {
+		case fileHint != "" && (fileName == fileHint || strings.HasSuffix(fileName, "/"+fileHint)):
+			return 0
+		case path.Base(fileName) == name+".sol":
+			return 1
+		}
+		return 2
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		rankI, rankJ := rank(candidates[i].fileName), rank(candidates[j].fileName)
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		return candidates[i].fileName < candidates[j].fileName
+	})
+
+	for i := range candidates {
+		candidates[i].comparison = compareDeployedCode(candidates[i].bytecode, deployedCode)
+		if candidates[i].comparison.Status != VerificationMismatch {
+			return candidates[i], nil
+		}
+	}
+
+	return candidates[0], nil
+}
+
+// Explorer contract names are either plain or fully qualified as path:Name
+func splitContractName(info etherscan.SourceCodeInfo) (string, string) {
+	if index := strings.LastIndex(info.ContractName, ":"); index >= 0 {
+		return info.ContractName[:index], info.ContractName[index+1:]
+	}
+
+	return info.ContractFileName, info.ContractName
+}
+
+// Compares compiled runtime code with the deployed code. Library addresses and immutables are only known
+// at deployment, so they are taken from the deployed code, and a differing metadata hash is a partial match.
+func compareDeployedCode(bytecode deployedBytecode, deployedCode string) CodeComparison {
+	deployed, _ := hex.DecodeString(strings.TrimPrefix(deployedCode, "0x"))
+	comparison := CodeComparison{DeployedLength: len(deployed)}
+
+	// Unlinked libraries are placeholders in the hex object, replace them before decoding
+	object := []byte(strings.TrimPrefix(bytecode.Object, "0x"))
+	comparison.CompiledLength = len(object) / 2
+	for _, libraries := range bytecode.LinkReferences {
+		for _, offsets := range libraries {
+			for _, offset := range offsets {
+				end := offset.Start + offset.Length
+				if end*2 > len(object) || end > len(deployed) {
+					return mismatch(comparison)
+				}
+				hex.Encode(object[offset.Start*2:end*2], deployed[offset.Start:end])
+				comparison.IgnoredRanges = append(comparison.IgnoredRanges, ByteRange{Start: offset.Start, End: end, Reason: "library"})
+			}
+		}
+	}
+
+	compiled, err := hex.DecodeString(string(object))
+	if err != nil {
+		return mismatch(comparison)
+	}
+
+	for _, offsets := range bytecode.ImmutableReferences {
+		for _, offset := range offsets {
+			end := offset.Start + offset.Length
+			if end > len(compiled) || end > len(deployed) {
+				return mismatch(comparison)
+			}
+			copy(compiled[offset.Start:end], deployed[offset.Start:end])
+			comparison.IgnoredRanges = append(comparison.IgnoredRanges, ByteRange{Start: offset.Start, End: end, Reason: "immutable"})
+		}
+	}
+	sort.Slice(comparison.IgnoredRanges, func(i, j int) bool {
+		return comparison.IgnoredRanges[i].Start < comparison.IgnoredRanges[j].Start
+	})
+
+	if bytes.Equal(compiled, deployed) {
+		comparison.Status = VerificationExactMatch
+		return comparison
+	}
+
+	compiledCode, deployedCodeWithoutMetadata := stripMetadata(compiled), stripMetadata(deployed)
+	if len(compiledCode) < len(compiled) && bytes.Equal(compiledCode, deployedCodeWithoutMetadata) {
+		comparison.Status = VerificationPartialMatch
+		comparison.Differences = []ByteRange{{Start: len(compiledCode), End: len(compiled), Reason: "metadata"}}
+		return comparison
+	}
+
+	comparison.Status = VerificationMismatch
+	comparison.Differences = diffRanges(compiled, deployed)
+	return comparison
+}
+
+func mismatch(comparison CodeComparison) CodeComparison {
+	comparison.Status = VerificationMismatch
+	comparison.Differences = []ByteRange{{Start: 0, End: max(comparison.CompiledLength, comparison.DeployedLength)}}
+	return comparison
+}
+
+// Ranges of bytes that differ, bytes past the end of the shorter code all differ
+func diffRanges(compiled []byte, deployed []byte) []ByteRange {
+	var ranges []ByteRange
+
+	for i := 0; i < max(len(compiled), len(deployed)); i++ {
+		if i < len(compiled) && i < len(deployed) && compiled[i] == deployed[i] {
+			continue
+		}
+
+		if len(ranges) > 0 && ranges[len(ranges)-1].End == i {
+			ranges[len(ranges)-1].End = i + 1
+		} else {
+			ranges = append(ranges, ByteRange{Start: i, End: i + 1})
+		}
+	}
+
+	return ranges
+}
+
+// Solidity appends CBOR metadata to the runtime code, its length is in the last two bytes
+func stripMetadata(code []byte) []byte {
+	if len(code) < 2 {
+		return code
+	}
+
+	length := int(code[len(code)-2])<<8 | int(code[len(code)-1])
+	if length+2 > len(code) {
+		return code
+	}
+
+	return code[:len(code)-length-2]
+}
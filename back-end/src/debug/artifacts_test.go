@@ -0,0 +1,143 @@
+package debug
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareDeployedCode(t *testing.T) {
+	libraryPlaceholder := "__$" + strings.Repeat("a", 34) + "$__"
+	libraryAddress := strings.Repeat("11", 20)
+
+	tests := []struct {
+		name         string
+		bytecode     deployedBytecode
+		deployedCode string
+		expected     CodeComparison
+	}{
+		{
+			name:         "exact match",
+			bytecode:     deployedBytecode{Object: "6080604052"},
+			deployedCode: "0x6080604052",
+			expected:     CodeComparison{Status: VerificationExactMatch, CompiledLength: 5, DeployedLength: 5},
+		},
+		{
+			name:         "metadata hash differs",
+			bytecode:     deployedBytecode{Object: "6001600201" + "a1aa0002"},
+			deployedCode: "0x6001600201" + "a1bb0002",
+			expected: CodeComparison{
+				Status:         VerificationPartialMatch,
+				CompiledLength: 9,
+				DeployedLength: 9,
+				Differences:    []ByteRange{{Start: 5, End: 9, Reason: "metadata"}},
+			},
+		},
+		{
+			name:         "code differs before the metadata",
+			bytecode:     deployedBytecode{Object: "6001600201" + "a1aa0002"},
+			deployedCode: "0x6001600301" + "a1aa0002",
+			expected: CodeComparison{
+				Status:         VerificationMismatch,
+				CompiledLength: 9,
+				DeployedLength: 9,
+				Differences:    []ByteRange{{Start: 3, End: 4}},
+			},
+		},
+		{
+			name: "immutables are taken from the deployed code",
+			bytecode: deployedBytecode{
+				Object:              "60000000",
+				ImmutableReferences: map[string][]immutableOffset{"12": {{Start: 1, Length: 2}}},
+			},
+			deployedCode: "0x60abcd00",
+			expected: CodeComparison{
+				Status:         VerificationExactMatch,
+				CompiledLength: 4,
+				DeployedLength: 4,
+				IgnoredRanges:  []ByteRange{{Start: 1, End: 3, Reason: "immutable"}},
+			},
+		},
+		{
+			name: "library placeholders are linked with the deployed addresses",
+			bytecode: deployedBytecode{
+				Object:         "73" + libraryPlaceholder + "00",
+				LinkReferences: map[string]map[string][]immutableOffset{"Lib.sol": {"Lib": {{Start: 1, Length: 20}}}},
+			},
+			deployedCode: "0x73" + libraryAddress + "00",
+			expected: CodeComparison{
+				Status:         VerificationExactMatch,
+				CompiledLength: 22,
+				DeployedLength: 22,
+				IgnoredRanges:  []ByteRange{{Start: 1, End: 21, Reason: "library"}},
+			},
+		},
+		{
+			name: "immutable past the deployed code",
+			bytecode: deployedBytecode{
+				Object:              "60000000",
+				ImmutableReferences: map[string][]immutableOffset{"12": {{Start: 2, Length: 4}}},
+			},
+			deployedCode: "0x60000000",
+			expected: CodeComparison{
+				Status:         VerificationMismatch,
+				CompiledLength: 4,
+				DeployedLength: 4,
+				Differences:    []ByteRange{{Start: 0, End: 4}},
+			},
+		},
+		{
+			name:         "deployed code is longer",
+			bytecode:     deployedBytecode{Object: "6001"},
+			deployedCode: "0x600160",
+			expected: CodeComparison{
+				Status:         VerificationMismatch,
+				CompiledLength: 2,
+				DeployedLength: 3,
+				Differences:    []ByteRange{{Start: 2, End: 3}},
+			},
+		},
+		{
+			name:         "unlinked placeholder without link references",
+			bytecode:     deployedBytecode{Object: "73" + libraryPlaceholder + "00"},
+			deployedCode: "0x73" + libraryAddress + "00",
+			expected: CodeComparison{
+				Status:         VerificationMismatch,
+				CompiledLength: 22,
+				DeployedLength: 22,
+				Differences:    []ByteRange{{Start: 0, End: 22}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			comparison := compareDeployedCode(test.bytecode, test.deployedCode)
+			if !reflect.DeepEqual(comparison, test.expected) {
+				t.Errorf("compareDeployedCode() = %+v, expected %+v", comparison, test.expected)
+			}
+		})
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     []byte
+		expected []byte
+	}{
+		{name: "metadata with its length", code: []byte{0x60, 0x01, 0xa1, 0xaa, 0x00, 0x02}, expected: []byte{0x60, 0x01}},
+		{name: "length longer than the code", code: []byte{0x60, 0x01, 0x00, 0x10}, expected: []byte{0x60, 0x01, 0x00, 0x10}},
+		{name: "too short for a length", code: []byte{0x60}, expected: []byte{0x60}},
+		{name: "empty metadata", code: []byte{0x60, 0x01, 0x00, 0x00}, expected: []byte{0x60, 0x01}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stripped := stripMetadata(test.code)
+			if !reflect.DeepEqual(stripped, test.expected) {
+				t.Errorf("stripMetadata() = %x, expected %x", stripped, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package debug
+
+import (
+	evm "Simulations/src/rpc"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Bundle transaction statuses
+const (
+	TxSuccess  = "success"
+	TxReverted = "reverted"
+	TxRejected = "rejected" // Refused by the node, e.g. bad nonce or insufficient funds
+	TxSkipped  = "skipped"  // Not executed because an earlier transaction failed
+)
+
+// SimulateBundle executes transactions in order on one fork, in a single block or one block per transaction
+func (s *Service) SimulateBundle(bundle BundleSimulation) (BundleResult, error) {
+	if len(bundle.Transactions) == 0 {
+		return BundleResult{}, errors.New("bundle has no transactions")
+	}
+
+	err := bundle.StateOverrides.Validate()
+	if err != nil {
+		return BundleResult{}, err
+	}
+
+	prefix, err := s.getBlockPrefix(bundle.BlockNumber, bundle.TxIndex)
+	if err != nil {
+		return BundleResult{}, err
+	}
+	blockNumber := getForkBlock(bundle.BlockNumber, prefix)
+
+	var forkId string
+	if blockNumber != "" {
+		forkId, err = s.forkService.CreateForkAtBlock(1, blockNumber)
+		fmt.Printf("🔄 Created fork %s for bundle simulation at block %s\n", forkId, blockNumber)
+	} else {
+		forkId, err = s.forkService.CreateFork(1)
+		fmt.Printf("🔄 Created fork %s for bundle simulation at latest block\n", forkId)
+	}
+	if err != nil {
+		return BundleResult{}, err
+	}
+	defer s.forkService.DeleteFork(forkId)
+
+	// Wait for the fork to start
+	fmt.Printf("⏳ Waiting 3 seconds for fork to start...\n")
+	time.Sleep(time.Second * 3)
+
+	overrides, _, err := s.resolveStateOverrides(forkId, bundle.StateOverrides)
+	if err != nil {
+		return BundleResult{}, err
+	}
+
+	// Unsigned transactions are sent from impersonated accounts
+	err = s.evmService.SetAutoImpersonate(forkId, true)
+	if err != nil {
+		return BundleResult{}, err
+	}
+
+	var results []BundleTransactionResult
+	if bundle.SameBlock {
+		results, err = s.executeInOneBlock(forkId, bundle, prefix, overrides)
+	} else {
+		results, err = s.executeInConsecutiveBlocks(forkId, bundle, prefix, overrides)
+	}
+	if err != nil {
+		return BundleResult{}, err
+	}
+
+	result := BundleResult{Success: true, Transactions: results}
+	for i := range result.Transactions {
+		transaction := &result.Transactions[i]
+		if transaction.Status != TxSuccess {
+			result.Success = false
+		}
+
+		if transaction.TxHash != "" {
+			fmt.Printf("🔍 Tracing bundle transaction #%d %s\n", i, transaction.TxHash)
+			s.traceBundleTransaction(forkId, transaction)
+		}
+	}
+
+	return result, nil
+}
+
+// Each transaction is mined as soon as it is sent
+func (s *Service) executeInConsecutiveBlocks(forkId string, bundle BundleSimulation, prefix *blockPrefix, overrides evm.StateOverride) ([]BundleTransactionResult, error) {
+	// The replayed transactions get their own block before the bundle starts
+	if prefix != nil {
+		replayedHashes, err := s.replayBlockPrefix(forkId, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.evmService.MineTx(forkId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.checkBlockPrefix(forkId, prefix, replayedHashes)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.evmService.SetAutomine(forkId, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.applyStateOverrides(forkId, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BundleTransactionResult, len(bundle.Transactions))
+	failed := false
+
+	for i, transaction := range bundle.Transactions {
+		results[i] = BundleTransactionResult{Index: i, Status: TxSkipped, Logs: []DecodedLog{}}
+		if failed && bundle.StopOnFailure {
+			continue
+		}
+
+		txHash, err := s.sendBundleTransaction(forkId, transaction)
+		if err != nil {
+			results[i].Status = TxRejected
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+
+		results[i].TxHash = txHash
+		status, err := s.getReceiptStatus(forkId, txHash)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i].Status = status
+		if status != TxSuccess {
+			failed = true
+		}
+	}
+
+	return results, nil
+}
+
+// Transactions are sent with automine disabled and mined together. When stopping on failure the block
+// is rolled back and mined again with only the transactions up to the first failure. Replayed block
+// transactions are sent again before every attempt so they share the block with the bundle.
+func (s *Service) executeInOneBlock(forkId string, bundle BundleSimulation, prefix *blockPrefix, overrides evm.StateOverride) ([]BundleTransactionResult, error) {
+	snapshot, err := s.evmService.GetCurrentSnapshot(forkId)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := bundle.Transactions
+	for {
+		replayedHashes, err := s.replayBlockPrefix(forkId, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		// The overrides only apply to the bundle, so the replayed transactions get their own block first
+		if prefix != nil && len(overrides) > 0 {
+			err = s.minePrefix(forkId, prefix, replayedHashes)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Reverting to the snapshot undoes the overrides, they are applied again on every attempt
+		err = s.applyStateOverrides(forkId, overrides)
+		if err != nil {
+			return nil, err
+		}
+
+		results, firstFailure, err := s.sendAndMine(forkId, transactions, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.checkBlockPrefix(forkId, prefix, replayedHashes)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bundle.StopOnFailure || firstFailure == -1 || firstFailure == len(transactions)-1 {
+			for i := len(results); i < len(bundle.Transactions); i++ {
+				results = append(results, BundleTransactionResult{Index: i, Status: TxSkipped, Logs: []DecodedLog{}})
+			}
+			return results, nil
+		}
+
+		err = s.evmService.RevertState(forkId, snapshot)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot, err = s.evmService.GetCurrentSnapshot(forkId)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = transactions[:firstFailure+1]
+	}
+}
+
+// Returns the results and the index of the first failed transaction, or -1. After a replayed prefix the
+// transactions have to follow it in its block, transactions the block had no room for are rejected.
+func (s *Service) sendAndMine(forkId string, transactions []BundleTransaction, prefix *blockPrefix) ([]BundleTransactionResult, int, error) {
+	results := make([]BundleTransactionResult, len(transactions))
+	firstFailure := -1
+
+	for i, transaction := range transactions {
+		results[i] = BundleTransactionResult{Index: i, Logs: []DecodedLog{}}
+
+		txHash, err := s.sendBundleTransaction(forkId, transaction)
+		if err != nil {
+			results[i].Status = TxRejected
+			results[i].Error = err.Error()
+			if firstFailure == -1 {
+				firstFailure = i
+			}
+			continue
+		}
+		results[i].TxHash = txHash
+	}
+
+	err := s.evmService.MineTx(forkId)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	var number uint64
+	position := 0
+	if prefix != nil {
+		number, position = prefix.nextPosition()
+	}
+
+	for i := range results {
+		if results[i].TxHash == "" {
+			continue
+		}
+
+		receipt, err := s.evmService.GetTransactionReceipt(forkId, results[i].TxHash)
+		if err != nil {
+			return nil, -1, err
+		}
+
+		if receipt.TransactionHash == "" {
+			results[i].Status = TxRejected
+			results[i].Error = "not included in the block, the block gas limit was reached"
+			results[i].TxHash = ""
+		} else {
+			blockNumber, txIndex := evm.ParseQuantity(receipt.BlockNumber), evm.ParseQuantity(receipt.TransactionIndex)
+			if prefix != nil && (blockNumber != number || txIndex != uint64(position)) {
+				return nil, -1, fmt.Errorf("bundle transaction #%d was mined at index %d of block %d instead of index %d of block %d", i, txIndex, blockNumber, position, number)
+			}
+			position++
+
+			results[i].Status = TxReverted
+			if receipt.Status == "0x1" {
+				results[i].Status = TxSuccess
+			}
+		}
+
+		if results[i].Status != TxSuccess && (firstFailure == -1 || i < firstFailure) {
+			firstFailure = i
+		}
+	}
+
+	return results, firstFailure, nil
+}
+
+func (s *Service) sendBundleTransaction(forkId string, transaction BundleTransaction) (string, error) {
+	if transaction.RawTransaction != "" {
+		return s.evmService.SendRawTransaction(forkId, transaction.RawTransaction)
+	}
+
+	if transaction.From == "" {
+		return "", errors.New("unsigned transaction requires a from address")
+	}
+
+	call, err := CallSimulation{
+		From:  transaction.From,
+		To:    transaction.To,
+		Data:  transaction.Data,
+		Value: transaction.Value,
+		Gas:   transaction.Gas,
+	}.toCallRequest()
+	if err != nil {
+		return "", err
+	}
+
+	return s.evmService.SendTransaction(forkId, call)
+}
+
+func (s *Service) getReceiptStatus(forkId string, txHash string) (string, error) {
+	receipt, err := s.evmService.GetTransactionReceipt(forkId, txHash)
+	if err != nil {
+		return "", err
+	}
+
+	if receipt.Status == "0x1" {
+		return TxSuccess, nil
+	}
+
+	return TxReverted, nil
+}
+
+// Fills gas, logs, revert and state diff of a mined bundle transaction, trace failures are reported but not fatal
+func (s *Service) traceBundleTransaction(forkId string, result *BundleTransactionResult) {
+	receipt, err := s.evmService.GetTransactionReceipt(forkId, result.TxHash)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	result.GasUsed = evm.ParseQuantity(receipt.GasUsed)
+	result.BlockNumber = evm.ParseQuantity(receipt.BlockNumber)
+
+	trace, err := s.evmService.GetTransactionTrace(forkId, result.TxHash)
+	if err != nil || len(trace) == 0 {
+		fmt.Printf("⚠️  Failed to get call trace of %s: %v\n", result.TxHash, err)
+	} else {
+		result.Logs = s.DecodeLogs(forkId, trace[0])
+
+		result.Revert = s.DecodeRevert(forkId, getCallTreeFrames(trace[0]))
+		if result.Revert != nil {
+			result.RevertReason = result.Revert.Reason
+		}
+	}
+
+	stateDiff, err := s.GetStateDiff(forkId, result.TxHash)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to get state diff of %s: %v\n", result.TxHash, err)
+	} else {
+		result.StateDiff = stateDiff
+	}
+}
@@ -0,0 +1,262 @@
+package debug
+
+import (
+	evm "Simulations/src/rpc"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Call frame as executed in the opcode trace
+type callFrame struct {
+	id             int
+	parentId       int
+	depth          int // Depth as reported in the struct logs
+	callType       string
+	codeAddress    string
+	storageAddress string
+	trace          evm.CallTrace
+	nextChild      int
+}
+
+func isCallOpcode(opcode string) bool {
+	switch opcode {
+	case "CALL", "CALLCODE", "DELEGATECALL", "STATICCALL", "CREATE", "CREATE2":
+		return true
+	}
+	return false
+}
+
+// resolveFrames walks the opcode trace with an explicit call stack and returns
+// the call frames together with the frame id executing each struct log
+func resolveFrames(rootFrame evm.CallTrace, structLogs []evm.StructLogs) ([]callFrame, []int) {
+	frames := []callFrame{{
+		id:             0,
+		parentId:       -1,
+		depth:          1,
+		callType:       rootFrame.Type,
+		codeAddress:    rootFrame.To,
+		storageAddress: rootFrame.To,
+		trace:          rootFrame,
+	}}
+	stack := []int{0}
+	frameIds := make([]int, len(structLogs))
+
+	for i, structLog := range structLogs {
+		// Frames are left on RETURN, REVERT, STOP or any exceptional halt
+		for len(stack) > 1 && frames[stack[len(stack)-1]].depth > structLog.Depth {
+			stack = stack[:len(stack)-1]
+		}
+
+		currentId := stack[len(stack)-1]
+		frameIds[i] = currentId
+
+		if !isCallOpcode(structLog.Op) {
+			continue
+		}
+
+		// Every executed call has a matching callTracer frame, in order
+		child, found := frames[currentId].takeNextChild()
+
+		// Calls to precompiles and accounts without code don't enter a new frame
+		if i+1 >= len(structLogs) || structLogs[i+1].Depth <= structLog.Depth {
+			continue
+		}
+
+		if !found {
+			fmt.Printf("⚠️  No callTracer frame for %s at step %d, using stack address\n", structLog.Op, i)
+			child = evm.CallTrace{Type: structLog.Op, To: getCallAddress(structLog)}
+		}
+
+		// Delegated code runs in the storage context of the caller
+		storageAddress := child.To
+		if structLog.Op == "DELEGATECALL" || structLog.Op == "CALLCODE" {
+			storageAddress = frames[currentId].storageAddress
+		}
+
+		frames = append(frames, callFrame{
+			id:             len(frames),
+			parentId:       currentId,
+			depth:          structLog.Depth + 1,
+			callType:       structLog.Op,
+			codeAddress:    child.To,
+			storageAddress: storageAddress,
+			trace:          child,
+		})
+		stack = append(stack, len(frames)-1)
+	}
+
+	return frames, frameIds
+}
+
+func (frame *callFrame) takeNextChild() (evm.CallTrace, bool) {
+	for frame.nextChild < len(frame.trace.Calls) {
+		child := frame.trace.Calls[frame.nextChild]
+		frame.nextChild++
+
+		// Self destructs are reported as frames but aren't entered through a call opcode
+		if child.Type == "SELFDESTRUCT" {
+			continue
+		}
+
+		return child, true
+	}
+
+	return evm.CallTrace{}, false
+}
+
+// Target address of a CALL-like opcode, taken from the stack
+func getCallAddress(structLog evm.StructLogs) string {
+	if strings.HasPrefix(structLog.Op, "CREATE") || len(structLog.Stack) < 2 {
+		return ""
+	}
+
+	value, success := new(big.Int).SetString(strings.TrimPrefix(fmt.Sprint(structLog.Stack[len(structLog.Stack)-2]), "0x"), 16)
+	if !success {
+		return ""
+	}
+
+	return fmt.Sprintf("0x%040x", value)
+}
+
+// getFrameContracts fetches the code and source of every frame, once per address
+func (s *Service) getFrameContracts(progress Progress, forkId string, frames []callFrame) (map[int]ContractEntry, error) {
+	contractsByAddress := make(map[string]ContractEntry)
+	frameContracts := make(map[int]ContractEntry)
+
+	for _, frame := range frames {
+		addressKey := strings.ToLower(frame.codeAddress)
+
+		contract, exists := contractsByAddress[addressKey]
+		if !exists {
+			err := checkCancelled(progress)
+			if err != nil {
+				return nil, err
+			}
+
+			report(progress, "  ", "[frame %d] Processing contract: %s", frame.id, frame.codeAddress)
+
+			contract, err = s.getContractEntry(progress, forkId, frame.codeAddress)
+			if err != nil {
+				fmt.Printf("❌ Failed to process contract %s: %v\n", frame.codeAddress, err)
+				return nil, err
+			}
+
+			contractsByAddress[addressKey] = contract
+		}
+
+		// Creation frames run the init code, which the runtime source map doesn't describe
+		if frame.callType == "CREATE" || frame.callType == "CREATE2" {
+			contract.bytecode = frame.trace.Input
+			contract.decompressedSourceMap = nil
+			contract.instructionIndexes = getInstructionIndexes(frame.trace.Input)
+		}
+
+		frameContracts[frame.id] = contract
+	}
+
+	return frameContracts, nil
+}
+
+// mapOpcodesToSource maps the relevant opcodes of a trace to the source line of the frame executing them
+func (s *Service) mapOpcodesToSource(structLogs []evm.StructLogs, frames []callFrame, frameIds []int, frameContracts map[int]ContractEntry) ([]CallTrace, error) {
+	var filteredOpcodes []CallTrace
+
+	for i, structLog := range structLogs {
+		frame := frames[frameIds[i]]
+		contract := frameContracts[frame.id]
+
+		if !isTargetOpcode(structLog.Op) {
+			continue
+		}
+
+		callTrace := CallTrace{
+			Opcode:          structLog.Op,
+			ContractAddress: contract.address,
+			StorageAddress:  frame.storageAddress,
+			FrameId:         frame.id,
+			Depth:           structLog.Depth,
+		}
+
+		// For unverified contracts, include only target opcodes with placeholder source info
+		if isUnverifiedContract(contract.sourceCodes) {
+			callTrace.LineNumber = 1
+			callTrace.File = "unverified.sol"
+			filteredOpcodes = append(filteredOpcodes, callTrace)
+			continue
+		}
+
+		opcodeNumber, err := s.getOpcodeNumber(structLog.Pc, contract.bytecode)
+		if err != nil || opcodeNumber >= len(contract.decompressedSourceMap) {
+			// Add opcode with placeholder info when PC can't be mapped
+			callTrace.LineNumber = -1
+			callTrace.File = "unknown"
+			filteredOpcodes = append(filteredOpcodes, callTrace)
+			continue
+		}
+
+		fileId := contract.decompressedSourceMap[opcodeNumber].FileID
+		jumpType := contract.decompressedSourceMap[opcodeNumber].JumpType
+
+		if structLog.Op == "JUMP" && jumpType == "-" {
+			continue
+		}
+
+		if !fileIdValid(fileId, contract.sourceCodes) {
+			fmt.Printf("⚠️  Invalid fileId %s, skipping\n", fileId)
+			continue
+		}
+
+		fileName, exists := contract.fileNames[fileId]
+		if !exists {
+			fmt.Printf("⚠️  FileId %s not found in fileNames map, skipping\n", fileId)
+			continue
+		}
+
+		sourceCode, exists := contract.sourceCodes[fileName]
+		if !exists {
+			fmt.Printf("⚠️  Source code not found for file %s, skipping\n", fileName)
+			continue
+		}
+
+		lineNumber, err := getLineNumber([]byte(sourceCode), contract.decompressedSourceMap[opcodeNumber].Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		lastOpcode := len(filteredOpcodes) - 1
+		if lastOpcode >= 0 && filteredOpcodes[lastOpcode].FrameId == frame.id && filteredOpcodes[lastOpcode].LineNumber == lineNumber && structLog.Op != "RETURN" {
+			continue
+		}
+
+		callTrace.LineNumber = lineNumber
+		callTrace.File = fileName
+		filteredOpcodes = append(filteredOpcodes, callTrace)
+	}
+
+	return filteredOpcodes, nil
+}
+
+// Call frames of a callTracer tree in call order, for when no opcode trace is available
+func getCallTreeFrames(rootFrame evm.CallTrace) []callFrame {
+	var frames []callFrame
+	addCallTreeFrame(rootFrame, -1, 1, &frames)
+	return frames
+}
+
+func addCallTreeFrame(trace evm.CallTrace, parentId int, depth int, frames *[]callFrame) {
+	id := len(*frames)
+	*frames = append(*frames, callFrame{
+		id:             id,
+		parentId:       parentId,
+		depth:          depth,
+		callType:       trace.Type,
+		codeAddress:    trace.To,
+		storageAddress: trace.To,
+		trace:          trace,
+	})
+
+	for _, call := range trace.Calls {
+		addCallTreeFrame(call, id, depth+1, frames)
+	}
+}
@@ -0,0 +1,201 @@
+package debug
+
+import (
+	evm "Simulations/src/rpc"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func (s *Service) GetGasProfile(forkId string, txHash string) (GasProfile, error) {
+	debugTrace, trace, err := s.getTraces(forkId, txHash, map[string]interface{}{})
+	if err != nil {
+		return GasProfile{}, err
+	}
+
+	profile := GasProfile{
+		TotalGasUsed: evm.ParseQuantity(trace[0].GasUsed),
+	}
+
+	// Call frames, inclusive gas comes from the call tracer
+	s.profileFrame(forkId, trace[0], -1, 0, nil, &profile)
+
+	functions := make(map[string]*FunctionGas)
+	for _, frame := range profile.Frames {
+		key := strings.ToLower(frame.ContractAddress) + ":" + frame.Function
+		if _, exists := functions[key]; !exists {
+			functions[key] = &FunctionGas{ContractAddress: frame.ContractAddress, Function: frame.Function}
+		}
+
+		functions[key].Calls++
+		functions[key].Inclusive += frame.Inclusive
+		functions[key].Exclusive += frame.Exclusive
+	}
+
+	for _, function := range functions {
+		profile.Functions = append(profile.Functions, *function)
+	}
+	sort.Slice(profile.Functions, func(i, j int) bool { return profile.Functions[i].Exclusive > profile.Functions[j].Exclusive })
+
+	// Source lines, gas of each opcode is attributed to the line it maps to
+	frames, frameIds := resolveFrames(trace[0], debugTrace.StructLogs)
+
+	frameContracts, err := s.getFrameContracts(noProgress{}, forkId, frames)
+	if err != nil {
+		return GasProfile{}, err
+	}
+
+	stepCosts := getStepCosts(debugTrace.StructLogs)
+	lines := make(map[string]*LineGas)
+
+	for i, structLog := range debugTrace.StructLogs {
+		contract := frameContracts[frameIds[i]]
+
+		fileName, lineNumber := "unknown", -1
+		if location, found := s.getSourceLocation(contract, structLog.Pc); found {
+			fileName, lineNumber = location.File, location.LineNumber
+		}
+
+		key := fmt.Sprintf("%v:%v:%v", strings.ToLower(contract.address), fileName, lineNumber)
+		if _, exists := lines[key]; !exists {
+			lines[key] = &LineGas{ContractAddress: contract.address, File: fileName, LineNumber: lineNumber}
+		}
+
+		lines[key].Gas += stepCosts[i]
+		lines[key].Steps++
+	}
+
+	for _, line := range lines {
+		profile.Lines = append(profile.Lines, *line)
+	}
+	sort.Slice(profile.Lines, func(i, j int) bool { return profile.Lines[i].Gas > profile.Lines[j].Gas })
+
+	return profile, nil
+}
+
+func (s *Service) profileFrame(forkId string, frame evm.CallTrace, parentId int, depth int, stack []string, profile *GasProfile) uint64 {
+	frameId := len(profile.Frames)
+	inclusive := evm.ParseQuantity(frame.GasUsed)
+
+	profile.Frames = append(profile.Frames, FrameGas{
+		Id:              frameId,
+		ParentId:        parentId,
+		Depth:           depth,
+		ContractAddress: frame.To,
+		CallType:        frame.Type,
+		Function:        s.getFunctionName(forkId, frame),
+		GasLimit:        evm.ParseQuantity(frame.Gas),
+		Inclusive:       inclusive,
+	})
+
+	stack = append(stack, frame.To+":"+profile.Frames[frameId].Function)
+
+	var childrenGas uint64
+	for _, call := range frame.Calls {
+		childrenGas += s.profileFrame(forkId, call, frameId, depth+1, stack, profile)
+	}
+
+	exclusive := uint64(0)
+	if inclusive > childrenGas {
+		exclusive = inclusive - childrenGas
+	}
+	profile.Frames[frameId].Exclusive = exclusive
+	profile.Frames[frameId].stack = strings.Join(stack, ";")
+
+	return inclusive
+}
+
+func (s *Service) getFunctionName(forkId string, frame evm.CallTrace) string {
+	if frame.Type == "CREATE" || frame.Type == "CREATE2" {
+		return "constructor"
+	}
+
+	if frame.Input == "0x" || frame.Input == "" {
+		return "receive"
+	}
+
+	method, _, err := s.getMethodAndParams(forkId, frame.To, frame.Input)
+	if err != nil {
+		if len(frame.Input) >= 10 {
+			return frame.Input[:10]
+		}
+		return "fallback"
+	}
+
+	return method.Sig
+}
+
+// Gas spent by each opcode itself, calls exclude the gas spent inside the callee
+func getStepCosts(structLogs []evm.StructLogs) []uint64 {
+	type pendingCall struct {
+		index     int
+		depth     int
+		childCost uint64
+	}
+
+	costs := make([]uint64, len(structLogs))
+	var pendingCalls []pendingCall
+
+	addToParent := func(cost uint64) {
+		if len(pendingCalls) > 0 {
+			pendingCalls[len(pendingCalls)-1].childCost += cost
+		}
+	}
+
+	for i, structLog := range structLogs {
+		// Back in the caller, so the call opcodes of finished frames can be settled
+		for len(pendingCalls) > 0 && pendingCalls[len(pendingCalls)-1].depth >= structLog.Depth {
+			call := pendingCalls[len(pendingCalls)-1]
+			pendingCalls = pendingCalls[:len(pendingCalls)-1]
+
+			inclusive := gasDifference(structLogs[call.index].Gas, structLog.Gas)
+			if inclusive > call.childCost {
+				costs[call.index] = inclusive - call.childCost
+			}
+			addToParent(inclusive)
+		}
+
+		if i == len(structLogs)-1 {
+			costs[i] = uint64(structLog.GasCost)
+			addToParent(costs[i])
+			continue
+		}
+
+		next := structLogs[i+1]
+		switch {
+		case next.Depth > structLog.Depth:
+			pendingCalls = append(pendingCalls, pendingCall{index: i, depth: structLog.Depth})
+		case next.Depth < structLog.Depth:
+			costs[i] = uint64(structLog.GasCost)
+			addToParent(costs[i])
+		default:
+			costs[i] = gasDifference(structLog.Gas, next.Gas)
+			addToParent(costs[i])
+		}
+	}
+
+	return costs
+}
+
+func gasDifference(before int, after int) uint64 {
+	if before < after {
+		return 0
+	}
+
+	return uint64(before - after)
+}
+
+// FoldedStacks renders the frame profile in the folded format used by flamegraph tools
+func (p GasProfile) FoldedStacks() string {
+	var builder strings.Builder
+
+	for _, frame := range p.Frames {
+		if frame.Exclusive == 0 {
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("%v %v\n", strings.ReplaceAll(frame.stack, " ", "_"), frame.Exclusive))
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,85 @@
+package debug
+
+import (
+	evm "Simulations/src/rpc"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DebugHistoricalTransaction debugs a mined transaction without an existing fork. The transaction is
+// executed again on a fork at its parent block, after the transactions that preceded it in its block.
+func (s *Service) DebugHistoricalTransaction(txHash string) (DebugResult, error) {
+	transaction, err := s.evmService.GetUpstreamTransaction(txHash)
+	if err != nil {
+		return DebugResult{}, err
+	}
+
+	blockNumber := strconv.FormatUint(evm.ParseQuantity(transaction.BlockNumber), 10)
+	txIndex := int(evm.ParseQuantity(transaction.TransactionIndex))
+
+	prefix, err := s.getBlockPrefix(blockNumber, &txIndex)
+	if err != nil {
+		return DebugResult{}, err
+	}
+
+	forkBlock := getForkBlock(blockNumber, prefix)
+	forkId, err := s.forkService.CreateForkAtBlock(1, forkBlock)
+	if err != nil {
+		return DebugResult{}, err
+	}
+	defer s.forkService.DeleteFork(forkId)
+	fmt.Printf("🔄 Created fork %s at block %s for transaction #%d of block %s\n", forkId, forkBlock, txIndex, blockNumber)
+
+	// Wait for the fork to start
+	fmt.Printf("⏳ Waiting 3 seconds for fork to start...\n")
+	time.Sleep(time.Second * 3)
+
+	replayedHashes, err := s.replayBlockPrefix(forkId, prefix)
+	if err != nil {
+		return DebugResult{}, err
+	}
+
+	// The replayed transaction is unsigned, so its hash on the fork differs from the original one
+	localTxHash, err := s.evmService.SendTransaction(forkId, getReplayCall(transaction))
+	if err != nil {
+		return DebugResult{}, err
+	}
+
+	err = s.evmService.MineTx(forkId)
+	if err != nil {
+		return DebugResult{}, err
+	}
+
+	err = s.checkBlockPrefix(forkId, prefix, replayedHashes)
+	if err != nil {
+		return DebugResult{}, err
+	}
+
+	// Traced at any other position the transaction would run on a different state than on chain
+	_, err = s.checkTransactionPosition(forkId, localTxHash, evm.ParseQuantity(prefix.block.Number), txIndex)
+	if err != nil {
+		return DebugResult{}, fmt.Errorf("replayed %s: %w", txHash, err)
+	}
+
+	fmt.Printf("✅ Replayed %s as %s\n", txHash, localTxHash)
+
+	fmt.Printf("🔍 Getting opcode trace FIRST...\n")
+	debugTrace, err := s.evmService.GetOpcodeTrace(forkId, localTxHash)
+	if err != nil {
+		return DebugResult{}, err
+	}
+	fmt.Printf("✅ Got opcode trace with %d struct logs\n", len(debugTrace.StructLogs))
+
+	// The replayed transaction only exists on this fork, so there is no helper fork for the call trace
+	trace, err := s.evmService.GetTransactionTrace(forkId, localTxHash)
+	if err != nil {
+		return DebugResult{}, err
+	}
+
+	if len(trace) == 0 {
+		return DebugResult{}, fmt.Errorf("no transaction trace for %s", txHash)
+	}
+
+	return s.buildDebugResult(forkId, forkId, localTxHash, debugTrace, trace)
+}
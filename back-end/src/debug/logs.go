@@ -0,0 +1,276 @@
+package debug
+
+import (
+	"Simulations/src/etherscan"
+	evm "Simulations/src/rpc"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Log together with the depth of the frame that emitted it
+type frameLog struct {
+	log   evm.CallLog
+	depth int
+}
+
+// DecodeLogs returns all logs of a call tree in the order they were emitted
+func (s *Service) DecodeLogs(forkId string, rootFrame evm.CallTrace) []DecodedLog {
+	var frameLogs []frameLog
+	collectLogs(rootFrame, 0, &frameLogs)
+
+	abiCache := make(map[string]*abi.ABI)
+	decodedLogs := []DecodedLog{}
+
+	for i, frameLog := range frameLogs {
+		decodedLog := DecodedLog{
+			Index:           i,
+			ContractAddress: frameLog.log.Address,
+			Depth:           frameLog.depth,
+			Topics:          frameLog.log.Topics,
+			Data:            frameLog.log.Data,
+		}
+
+		eventName, eventSignature, arguments, err := s.decodeLog(forkId, frameLog.log, abiCache)
+		if err != nil {
+			fmt.Printf("⚠️  Could not decode log #%d of %s: %v\n", i, frameLog.log.Address, err)
+		} else {
+			decodedLog.EventName = eventName
+			decodedLog.EventSignature = eventSignature
+			decodedLog.Arguments = arguments
+		}
+
+		decodedLogs = append(decodedLogs, decodedLog)
+	}
+
+	return decodedLogs
+}
+
+// Logs of a frame are interleaved with its subcalls based on their position
+func collectLogs(frame evm.CallTrace, depth int, result *[]frameLog) {
+	logIndex := 0
+
+	for i, call := range frame.Calls {
+		for logIndex < len(frame.Logs) && evm.ParseQuantity(frame.Logs[logIndex].Position) <= uint64(i) {
+			*result = append(*result, frameLog{log: frame.Logs[logIndex], depth: depth})
+			logIndex++
+		}
+
+		collectLogs(call, depth+1, result)
+	}
+
+	for ; logIndex < len(frame.Logs); logIndex++ {
+		*result = append(*result, frameLog{log: frame.Logs[logIndex], depth: depth})
+	}
+}
+
+func (s *Service) decodeLog(forkId string, log evm.CallLog, abiCache map[string]*abi.ABI) (string, string, []Argument, error) {
+	if len(log.Topics) == 0 {
+		return "", "", nil, errors.New("anonymous event")
+	}
+
+	// Logs of a proxy are emitted by the implementation code
+	for _, abiAddress := range s.getAbiAddresses(forkId, log.Address) {
+		parsedAbi, err := s.getCachedAbi(abiAddress, abiCache)
+		if err != nil {
+			continue
+		}
+
+		event, err := parsedAbi.EventByID(common.HexToHash(log.Topics[0]))
+		if err != nil {
+			continue
+		}
+
+		arguments, err := decodeEventArguments(event.Inputs, log.Topics, log.Data)
+		if err == nil {
+			return event.Name, event.Sig, arguments, nil
+		}
+	}
+
+	// Fall back to the event signature database
+	eventSignature, err := s.signatureService.GetEventSignature(log.Topics[0])
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	eventName, inputs, err := parseEventSignature(eventSignature, len(log.Topics)-1)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	arguments, err := decodeEventArguments(inputs, log.Topics, log.Data)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return eventName, eventSignature, arguments, nil
+}
+
+func (s *Service) getCachedAbi(address string, abiCache map[string]*abi.ABI) (*abi.ABI, error) {
+	address = strings.ToLower(address)
+
+	if parsedAbi, exists := abiCache[address]; exists {
+		if parsedAbi == nil {
+			return nil, errors.New("no ABI available")
+		}
+		return parsedAbi, nil
+	}
+
+	contractAbi, err := s.getAbi(address)
+	if err != nil {
+		abiCache[address] = nil
+		return nil, err
+	}
+
+	parsedAbi, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		abiCache[address] = nil
+		return nil, err
+	}
+
+	abiCache[address] = &parsedAbi
+	return &parsedAbi, nil
+}
+
+func decodeEventArguments(inputs abi.Arguments, topics []string, data string) ([]Argument, error) {
+	// Unnamed arguments would collide in the decoded maps
+	namedInputs := make(abi.Arguments, len(inputs))
+	for i, input := range inputs {
+		namedInputs[i] = input
+		if namedInputs[i].Name == "" {
+			namedInputs[i].Name = fmt.Sprintf("arg%d", i)
+		}
+	}
+
+	var indexed abi.Arguments
+	for _, input := range namedInputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+
+	if len(indexed) != len(topics)-1 {
+		return nil, errors.New("indexed arguments don't match topics")
+	}
+
+	var topicHashes []common.Hash
+	for _, topic := range topics[1:] {
+		topicHashes = append(topicHashes, common.HexToHash(topic))
+	}
+
+	values := make(map[string]interface{})
+	err := abi.ParseTopicsIntoMap(values, indexed, topicHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	rawData, err := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = namedInputs.NonIndexed().UnpackIntoMap(values, rawData)
+	if err != nil {
+		return nil, err
+	}
+
+	var arguments []Argument
+	for i, input := range namedInputs {
+		arguments = append(arguments, Argument{
+			Name:  inputs[i].Name,
+			Type:  input.Type.String(),
+			Value: fmt.Sprint(values[input.Name]),
+		})
+	}
+
+	return arguments, nil
+}
+
+// Builds event inputs from a text signature, assuming the leading arguments are the indexed ones
+func parseEventSignature(signature string, indexedCount int) (string, abi.Arguments, error) {
+	openIndex := strings.Index(signature, "(")
+	if openIndex <= 0 || !strings.HasSuffix(signature, ")") {
+		return "", nil, fmt.Errorf("invalid event signature: %s", signature)
+	}
+
+	eventName := signature[:openIndex]
+	paramTypes := splitTopLevel(signature[openIndex+1 : len(signature)-1])
+
+	if indexedCount > len(paramTypes) {
+		return "", nil, errors.New("more topics than event arguments")
+	}
+
+	var inputs abi.Arguments
+	for i, paramType := range paramTypes {
+		parsedType, err := abi.NewType(paramType, "", nil)
+		if err != nil {
+			return "", nil, err
+		}
+
+		inputs = append(inputs, abi.Argument{
+			Name:    fmt.Sprintf("arg%d", i),
+			Type:    parsedType,
+			Indexed: i < indexedCount,
+		})
+	}
+
+	return eventName, inputs, nil
+}
+
+// Splits a parameter list on commas outside of tuples
+func splitTopLevel(params string) []string {
+	var parts []string
+	depth := 0
+	start := 0
+
+	for i, char := range params {
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, params[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if start < len(params) {
+		parts = append(parts, params[start:])
+	}
+
+	return parts
+}
+
+// Explorer ABI through the in-memory cache, contracts without a verified ABI are remembered for a while.
+// ABIs of registered local sources come first.
+func (s *Service) getAbi(address string) (string, error) {
+	if localSource, found := s.localSources.Get(address); found && localSource.Abi != "" {
+		return localSource.Abi, nil
+	}
+
+	if contractAbi, verified, found := s.cacheService.GetAbi(address); found {
+		if !verified {
+			return "", etherscan.ErrNotVerified
+		}
+		return contractAbi, nil
+	}
+
+	contractAbi, err := s.sourcesService.GetAbi(address)
+	if errors.Is(err, etherscan.ErrNotVerified) {
+		s.cacheService.PutAbi(address, "")
+		return "", err
+	}
+	if err != nil {
+		return "", err
+	}
+
+	s.cacheService.PutAbi(address, contractAbi)
+	return contractAbi, nil
+}
@@ -0,0 +1,218 @@
+package debug
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Navigation commands
+const (
+	CommandContinue = "continue"
+	CommandStepInto = "stepInto"
+	CommandStepOver = "stepOver"
+	CommandStepOut  = "stepOut"
+)
+
+// Breakpoint types
+const (
+	BreakpointLine     = "line"
+	BreakpointFunction = "function"
+	BreakpointOpcode   = "opcode"
+	BreakpointRevert   = "revert"
+)
+
+// Reasons a navigation stopped
+const (
+	StopBreakpoint = "breakpoint"
+	StopStep       = "step"
+	StopEnd        = "end"
+)
+
+// Navigate runs a debugger command from a step and returns the step where execution stops.
+// Every command, not just continue, stops early when a breakpoint is hit.
+func (s *Service) Navigate(sessionId string, request NavigationRequest) (NavigationResult, error) {
+	session, err := s.getStepSession(sessionId)
+	if err != nil {
+		return NavigationResult{}, err
+	}
+
+	from := request.From
+	if from < 0 || from >= len(session.steps) {
+		return NavigationResult{}, fmt.Errorf("step %d out of range", from)
+	}
+
+	for _, breakpoint := range request.Breakpoints {
+		err := validateBreakpoint(breakpoint)
+		if err != nil {
+			return NavigationResult{}, err
+		}
+	}
+
+	var isTarget func(index int) bool
+	levels := session.getFunctionLevels()
+	current := session.steps[from]
+
+	switch request.Command {
+	case CommandContinue:
+		isTarget = func(index int) bool { return false }
+	case CommandStepInto:
+		isTarget = func(index int) bool {
+			return session.isNewLine(index) && !session.isSameLine(index, from)
+		}
+	case CommandStepOver:
+		isTarget = func(index int) bool {
+			step := session.steps[index]
+			if step.Depth < current.Depth {
+				return true
+			}
+			return step.FrameId == current.FrameId && levels[index] <= levels[from] &&
+				session.isNewLine(index) && !session.isSameLine(index, from)
+		}
+	case CommandStepOut:
+		isTarget = func(index int) bool {
+			step := session.steps[index]
+			return step.Depth < current.Depth || (step.FrameId == current.FrameId && levels[index] < levels[from])
+		}
+	default:
+		return NavigationResult{}, errors.New("unknown command " + request.Command)
+	}
+
+	for i := from + 1; i < len(session.steps); i++ {
+		for _, breakpoint := range request.Breakpoints {
+			if session.hitsBreakpoint(i, breakpoint) {
+				hit := breakpoint
+				return NavigationResult{Step: session.steps[i], Reason: StopBreakpoint, Breakpoint: &hit}, nil
+			}
+		}
+
+		if isTarget(i) {
+			return NavigationResult{Step: session.steps[i], Reason: StopStep}, nil
+		}
+	}
+
+	return NavigationResult{Step: session.steps[len(session.steps)-1], Reason: StopEnd}, nil
+}
+
+func validateBreakpoint(breakpoint Breakpoint) error {
+	switch breakpoint.Type {
+	case BreakpointLine:
+		if breakpoint.File == "" || breakpoint.Line <= 0 {
+			return errors.New("line breakpoint requires file and line")
+		}
+	case BreakpointFunction:
+		if breakpoint.Address == "" {
+			return errors.New("function breakpoint requires address")
+		}
+	case BreakpointOpcode:
+		if breakpoint.Opcode == "" {
+			return errors.New("opcode breakpoint requires opcode")
+		}
+	case BreakpointRevert:
+	default:
+		return errors.New("unknown breakpoint type " + breakpoint.Type)
+	}
+
+	return nil
+}
+
+func (session *stepSession) hitsBreakpoint(index int, breakpoint Breakpoint) bool {
+	step := session.steps[index]
+
+	switch breakpoint.Type {
+	case BreakpointLine:
+		return session.isNewLine(index) && step.Source.LineNumber == breakpoint.Line && matchesFile(step.Source.File, breakpoint.File)
+
+	case BreakpointFunction:
+		// Only the first step of a frame enters the function
+		if index > 0 && session.frameIds[index-1] == step.FrameId {
+			return false
+		}
+
+		frame := session.frames[step.FrameId]
+		if !strings.EqualFold(frame.codeAddress, breakpoint.Address) && !strings.EqualFold(frame.storageAddress, breakpoint.Address) {
+			return false
+		}
+
+		if breakpoint.Selector == "" {
+			return true
+		}
+		return len(frame.trace.Input) >= 10 && strings.EqualFold(frame.trace.Input[:10], breakpoint.Selector)
+
+	case BreakpointOpcode:
+		if !strings.EqualFold(step.Opcode, breakpoint.Opcode) {
+			return false
+		}
+
+		if breakpoint.Address != "" && !strings.EqualFold(step.StorageAddress, breakpoint.Address) {
+			return false
+		}
+
+		// Storage opcodes take the slot from the top of the stack
+		if breakpoint.Slot != "" {
+			if len(step.Stack) == 0 {
+				return false
+			}
+			return normalizeSlot(step.Stack[len(step.Stack)-1]) == normalizeSlot(breakpoint.Slot)
+		}
+		return true
+
+	case BreakpointRevert:
+		return step.Opcode == "REVERT" || step.Error != ""
+	}
+
+	return false
+}
+
+// A step starts a new line when it has a source and the previous step of its frame was on another line
+func (session *stepSession) isNewLine(index int) bool {
+	if session.steps[index].Source == nil {
+		return false
+	}
+
+	return index == 0 || !session.isSameLine(index, index-1)
+}
+
+func (session *stepSession) isSameLine(first int, second int) bool {
+	firstStep := session.steps[first]
+	secondStep := session.steps[second]
+
+	if firstStep.Source == nil || secondStep.Source == nil || firstStep.FrameId != secondStep.FrameId {
+		return false
+	}
+
+	return firstStep.Source.File == secondStep.Source.File && firstStep.Source.LineNumber == secondStep.Source.LineNumber
+}
+
+// Number of internal function calls entered within the frame at every step, from the source map jump types
+func (session *stepSession) getFunctionLevels() []int {
+	levels := make([]int, len(session.steps))
+	frameLevels := make(map[int]int)
+
+	for i, step := range session.steps {
+		levels[i] = frameLevels[step.FrameId]
+
+		if step.Opcode != "JUMP" {
+			continue
+		}
+
+		entry, found := getSourceMapEntry(session.frameContracts[step.FrameId], step.Pc)
+		if !found {
+			continue
+		}
+
+		switch entry.JumpType {
+		case "i":
+			frameLevels[step.FrameId]++
+		case "o":
+			frameLevels[step.FrameId]--
+		}
+	}
+
+	return levels
+}
+
+// Breakpoint files can be given as the full source path or only its last components
+func matchesFile(file string, breakpointFile string) bool {
+	return file == breakpointFile || strings.HasSuffix(file, "/"+breakpointFile)
+}
@@ -0,0 +1,140 @@
+package debug
+
+import (
+	evm "Simulations/src/rpc"
+	"fmt"
+	"strings"
+)
+
+// Validate rejects overrides that can't be applied to a fork before sending transactions. Replacing the
+// whole storage with state would need the other slots of the forked account cleared, which the fork can't
+// enumerate. Call simulations don't need it, debug_traceCall applies state itself.
+func (overrides StateOverride) Validate() error {
+	for address, override := range overrides {
+		if override.State != nil {
+			return fmt.Errorf("override of %s sets state, which only call simulations support, use stateDiff instead", address)
+		}
+	}
+
+	return nil
+}
+
+// resolveStateOverrides compiles patched sources into code and normalizes quantities and storage words.
+// The compiled patches are returned by address so their frames can be mapped to the patched source.
+func (s *Service) resolveStateOverrides(forkId string, overrides StateOverride) (evm.StateOverride, map[string]ContractEntry, error) {
+	resolved := make(evm.StateOverride)
+	patches := make(map[string]ContractEntry)
+
+	for address, override := range overrides {
+		account := override.AccountOverride
+
+		if len(override.PatchedSources) > 0 {
+			if account.Code != "" {
+				return nil, nil, fmt.Errorf("override of %s sets both code and patched sources", address)
+			}
+
+			patch, err := s.compilePatchedContract(forkId, address, override.PatchedSources)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to compile patched sources of %s: %w", address, err)
+			}
+			account.Code = patch.bytecode
+			patches[strings.ToLower(address)] = patch
+		}
+
+		var err error
+		account.Balance, err = toQuantity(account.Balance)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid balance override of %s: %w", address, err)
+		}
+
+		account.Nonce, err = toQuantity(account.Nonce)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid nonce override of %s: %w", address, err)
+		}
+
+		account.State = normalizeStorage(account.State)
+		account.StateDiff = normalizeStorage(account.StateDiff)
+
+		resolved[strings.ToLower(address)] = account
+	}
+
+	return resolved, patches, nil
+}
+
+// applyStateOverrides writes the overrides into the fork state. A full state override can't be written,
+// it only reaches call simulations, where debug_traceCall applies it to the traced call.
+func (s *Service) applyStateOverrides(forkId string, overrides evm.StateOverride) error {
+	for address, account := range overrides {
+		if account.Balance != "" {
+			_, err := s.evmService.SetBalance(forkId, address, account.Balance)
+			if err != nil {
+				return err
+			}
+		}
+
+		if account.Nonce != "" {
+			err := s.evmService.SetNonce(forkId, address, account.Nonce)
+			if err != nil {
+				return err
+			}
+		}
+
+		if account.Code != "" {
+			err := s.evmService.SetCode(forkId, address, account.Code)
+			if err != nil {
+				return err
+			}
+		}
+
+		for slot, value := range account.StateDiff {
+			err := s.evmService.ChangeStorageSlot(forkId, address, value, slot)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("✅ Applied state overrides for %d accounts\n", len(overrides))
+	return nil
+}
+
+// useOverriddenCode points the frames of overridden accounts at the code that actually ran. Patched
+// contracts map to their patched source, other replaced code has no source map.
+func useOverriddenCode(frameContracts map[int]ContractEntry, frames []callFrame, overrides evm.StateOverride, patches map[string]ContractEntry) {
+	for _, frame := range frames {
+		if frame.callType == "CREATE" || frame.callType == "CREATE2" {
+			continue
+		}
+
+		address := strings.ToLower(frame.codeAddress)
+		if patch, exists := patches[address]; exists {
+			frameContracts[frame.id] = patch
+			continue
+		}
+
+		code := overrides[address].Code
+		if code == "" {
+			continue
+		}
+
+		contract := frameContracts[frame.id]
+		contract.bytecode = code
+		contract.decompressedSourceMap = nil
+		contract.instructionIndexes = getInstructionIndexes(code)
+		frameContracts[frame.id] = contract
+	}
+}
+
+// Storage keys and values are full 32 byte words in overrides
+func normalizeStorage(storage map[string]string) map[string]string {
+	if storage == nil {
+		return nil
+	}
+
+	normalized := make(map[string]string)
+	for slot, value := range storage {
+		normalized["0x"+normalizeSlot(slot)] = "0x" + normalizeSlot(value)
+	}
+
+	return normalized
+}
@@ -0,0 +1,198 @@
+package debug
+
+import (
+	"Simulations/src/etherscan"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Compiled runtime code, the immutables it references by name and its source map
+type compiledBytecode struct {
+	code       []byte
+	immutables map[string][]immutableOffset
+	sourceMap  string
+	fileNames  map[string]string
+	asts       map[string]json.RawMessage
+}
+
+// compilePatchedContract compiles the verified source of a contract with some files replaced, using the
+// original compiler settings. Immutables are copied from the deployed code so the patch keeps its state.
+// The entry maps the patched code to the patched source.
+func (s *Service) compilePatchedContract(forkId string, address string, patchedSources map[string]string) (ContractEntry, error) {
+	deployedCode, err := s.evmService.GetContractBytecode(forkId, address)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	info, verified, err := s.getSourceCodeInfo(address, getCodeHash(deployedCode))
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	if !verified {
+		return ContractEntry{}, fmt.Errorf("contract %s has no verified source to patch", address)
+	}
+
+	if isVyper(info) {
+		return ContractEntry{}, fmt.Errorf("contract %s is written in Vyper, only Solidity sources can be patched", address)
+	}
+
+	originalInput, err := buildStandardJsonInput(info, address)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	patchedInput, err := buildStandardJsonInput(info, address)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	sourceCodes, err := getSourceFiles(info, address)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	sources, _ := patchedInput["sources"].(map[string]interface{})
+	for fileName, content := range patchedSources {
+		if _, exists := sources[fileName]; !exists {
+			return ContractEntry{}, fmt.Errorf("unknown source file %s", fileName)
+		}
+		sources[fileName] = map[string]interface{}{"content": content}
+		sourceCodes[fileName] = content
+	}
+
+	solc, err := s.compilerService.GetSolc(info.CompilerVersion)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	original, err := compileBytecode(solc, info, originalInput, deployedCode)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	patched, err := compileBytecode(solc, info, patchedInput, deployedCode)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	if len(patched.immutables) > 0 {
+		err = copyImmutables(patched, original, deployedCode)
+		if err != nil {
+			return ContractEntry{}, err
+		}
+	}
+
+	code := "0x" + hex.EncodeToString(patched.code)
+	contract := ContractEntry{
+		address:               address,
+		bytecode:              code,
+		sourceCodes:           sourceCodes,
+		fileNames:             patched.fileNames,
+		decompressedSourceMap: decompressSourceMap(patched.sourceMap),
+		instructionIndexes:    getInstructionIndexes(code),
+		asts:                  patched.asts,
+	}
+
+	return contract, nil
+}
+
+// Copies the values of the immutables the original code shares with the patch from the deployed code
+func copyImmutables(patched compiledBytecode, original compiledBytecode, deployedCode string) error {
+	deployed, err := hex.DecodeString(strings.TrimPrefix(deployedCode, "0x"))
+	if err != nil {
+		return err
+	}
+
+	if len(deployed) != len(original.code) {
+		return errors.New("recompiled code doesn't match the deployed code, immutables can't be copied")
+	}
+
+	for name, offsets := range patched.immutables {
+		originalOffsets, exists := original.immutables[name]
+		if !exists || len(originalOffsets) == 0 {
+			fmt.Printf("⚠️  Immutable %s is new in the patched code, leaving it zero\n", name)
+			continue
+		}
+
+		source := originalOffsets[0]
+		value := deployed[source.Start : source.Start+source.Length]
+		for _, offset := range offsets {
+			copy(patched.code[offset.Start:offset.Start+offset.Length], value)
+		}
+	}
+
+	return nil
+}
+
+func compileBytecode(solc string, info etherscan.SourceCodeInfo, input map[string]interface{}, deployedCode string) (compiledBytecode, error) {
+	output, err := runSolc(solc, input)
+	if err != nil {
+		return compiledBytecode{}, err
+	}
+
+	contract, err := selectContract(output, info, deployedCode)
+	if err != nil {
+		return compiledBytecode{}, err
+	}
+
+	code, err := hex.DecodeString(strings.TrimPrefix(contract.bytecode.Object, "0x"))
+	if err != nil {
+		return compiledBytecode{}, err
+	}
+
+	names := getImmutableNames(output.Sources)
+	immutables := make(map[string][]immutableOffset)
+	for id, offsets := range contract.bytecode.ImmutableReferences {
+		immutables[names[id]] = offsets
+	}
+
+	fileNames := make(map[string]string)
+	asts := make(map[string]json.RawMessage)
+	for fileName, source := range output.Sources {
+		fileNames[fmt.Sprintf("%v", source.Id)] = fileName
+		if len(source.Ast) > 0 {
+			asts[fileName] = source.Ast
+		}
+	}
+
+	compiled := compiledBytecode{
+		code:       code,
+		immutables: immutables,
+		sourceMap:  contract.bytecode.SourceMap,
+		fileNames:  fileNames,
+		asts:       asts,
+	}
+
+	return compiled, nil
+}
+
+// Names of immutable variables by AST id, ids change between compilations while names don't
+func getImmutableNames(sources map[string]Source) map[string]string {
+	names := make(map[string]string)
+
+	for _, source := range sources {
+		var ast interface{}
+		if json.Unmarshal(source.Ast, &ast) != nil {
+			continue
+		}
+
+		walkAst(ast, func(node map[string]interface{}) {
+			if node["nodeType"] != "VariableDeclaration" {
+				return
+			}
+
+			id, isNumber := node["id"].(float64)
+			name, _ := node["name"].(string)
+			if isNumber {
+				names[strconv.Itoa(int(id))] = name
+			}
+		})
+	}
+
+	return names
+}
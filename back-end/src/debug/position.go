@@ -0,0 +1,244 @@
+package debug
+
+import (
+	evm "Simulations/src/rpc"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Transactions of a historical block executed before the simulated position
+type blockPrefix struct {
+	block evm.Block
+	calls []evm.CallRequest
+	mined bool // Mined in its own block, the simulated transactions start the next one
+}
+
+// getBlockPrefix fetches the first txIndex transactions of a block, or nil when no index is given
+func (s *Service) getBlockPrefix(blockNumber string, txIndex *int) (*blockPrefix, error) {
+	if txIndex == nil {
+		return nil, nil
+	}
+
+	if blockNumber == "" {
+		return nil, errors.New("txIndex requires a blockNumber")
+	}
+
+	number, err := parseBlockNumber(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if number == 0 {
+		return nil, errors.New("the genesis block has no parent to fork from")
+	}
+
+	block, err := s.evmService.GetUpstreamBlock(number)
+	if err != nil {
+		return nil, err
+	}
+
+	if *txIndex < 0 || *txIndex > len(block.Transactions) {
+		return nil, fmt.Errorf("txIndex %d out of range, block %d has %d transactions", *txIndex, number, len(block.Transactions))
+	}
+
+	prefix := &blockPrefix{block: block}
+	for _, transaction := range block.Transactions[:*txIndex] {
+		prefix.calls = append(prefix.calls, getReplayCall(transaction))
+	}
+
+	return prefix, nil
+}
+
+// Unsigned copy of a historical transaction, sent again from its impersonated sender
+func getReplayCall(transaction evm.BlockTransaction) evm.CallRequest {
+	call := evm.CallRequest{
+		From:  transaction.From,
+		To:    transaction.To,
+		Data:  transaction.Input,
+		Value: transaction.Value,
+		Gas:   transaction.Gas,
+		Nonce: transaction.Nonce,
+	}
+
+	// Dynamic fee transactions can't be sent with a gas price
+	if transaction.MaxFeePerGas != "" {
+		call.MaxFeePerGas = transaction.MaxFeePerGas
+		call.MaxPriorityFeePerGas = transaction.MaxPriorityFeePerGas
+	} else {
+		call.GasPrice = transaction.GasPrice
+	}
+
+	return call
+}
+
+// Block to fork from, the parent block when a prefix has to be replayed
+func getForkBlock(blockNumber string, prefix *blockPrefix) string {
+	if prefix == nil {
+		return blockNumber
+	}
+
+	return strconv.FormatUint(evm.ParseQuantity(prefix.block.Number)-1, 10)
+}
+
+// replayBlockPrefix sets up the context of the historical block and sends its earlier transactions from
+// impersonated senders, returning their hashes on the fork. Automine is left disabled so the simulated
+// transactions join the same block, which has to be checked with checkBlockPrefix once mined.
+func (s *Service) replayBlockPrefix(forkId string, prefix *blockPrefix) ([]string, error) {
+	err := s.evmService.SetAutomine(forkId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix == nil {
+		return nil, nil
+	}
+
+	err = s.evmService.SetAutoImpersonate(forkId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.evmService.SetNextBlockTimestamp(forkId, prefix.block.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.evmService.SetCoinbase(forkId, prefix.block.Miner)
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix.block.BaseFeePerGas != "" {
+		err = s.evmService.SetNextBlockBaseFee(forkId, prefix.block.BaseFeePerGas)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var replayedHashes []string
+	for i, call := range prefix.calls {
+		txHash, err := s.evmService.SendTransaction(forkId, call)
+		if err != nil {
+			return nil, fmt.Errorf("failed to replay transaction %d of block %s: %w", i, prefix.block.Number, err)
+		}
+		replayedHashes = append(replayedHashes, txHash)
+	}
+
+	fmt.Printf("✅ Replayed %d transactions of block %s\n", len(prefix.calls), prefix.block.Number)
+	return replayedHashes, nil
+}
+
+// minePrefix mines the replayed transactions in their own block, so state overrides can be applied on
+// top of them before the simulated transactions run. Those start the next block, with the base fee of the
+// historical block and a timestamp one second later.
+func (s *Service) minePrefix(forkId string, prefix *blockPrefix, replayedHashes []string) error {
+	err := s.evmService.MineTx(forkId)
+	if err != nil {
+		return err
+	}
+
+	err = s.checkBlockPrefix(forkId, prefix, replayedHashes)
+	if err != nil {
+		return err
+	}
+	prefix.mined = true
+
+	timestamp := fmt.Sprintf("0x%x", evm.ParseQuantity(prefix.block.Timestamp)+1)
+	err = s.evmService.SetNextBlockTimestamp(forkId, timestamp)
+	if err != nil {
+		return err
+	}
+
+	if prefix.block.BaseFeePerGas != "" {
+		err = s.evmService.SetNextBlockBaseFee(forkId, prefix.block.BaseFeePerGas)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✅ Mined the %d replayed transactions of block %s on their own\n", len(prefix.calls), prefix.block.Number)
+	return nil
+}
+
+// Block and index the first simulated transaction is mined at
+func (prefix *blockPrefix) nextPosition() (uint64, int) {
+	number := evm.ParseQuantity(prefix.block.Number)
+	if prefix.mined {
+		return number + 1, 0
+	}
+
+	return number, len(prefix.calls)
+}
+
+// checkBlockPrefix makes sure the mined replay matches the historical block: every transaction at its
+// original index, and only reverting when it also reverted on chain, otherwise the state is not the one
+// the simulated position would have seen
+func (s *Service) checkBlockPrefix(forkId string, prefix *blockPrefix, replayedHashes []string) error {
+	if prefix == nil {
+		return nil
+	}
+
+	for i, txHash := range replayedHashes {
+		originalHash := prefix.block.Transactions[i].Hash
+
+		receipt, err := s.checkTransactionPosition(forkId, txHash, evm.ParseQuantity(prefix.block.Number), i)
+		if err != nil {
+			return fmt.Errorf("replayed transaction %s: %w", originalHash, err)
+		}
+
+		if receipt.Status == "0x1" {
+			continue
+		}
+
+		originalReceipt, err := s.evmService.GetUpstreamTransactionReceipt(originalHash)
+		if err != nil {
+			return err
+		}
+
+		if originalReceipt.Status == "0x1" {
+			return fmt.Errorf("transaction %d of block %s (%s) reverted when replayed but succeeded on chain", i, prefix.block.Number, originalHash)
+		}
+	}
+
+	return nil
+}
+
+// checkTransactionPosition makes sure a transaction was mined at index of the given block
+func (s *Service) checkTransactionPosition(forkId string, txHash string, number uint64, index int) (evm.TransactionReceipt, error) {
+	receipt, err := s.evmService.GetTransactionReceipt(forkId, txHash)
+	if err != nil {
+		return evm.TransactionReceipt{}, err
+	}
+
+	// Transactions left out of the mined block, e.g. when the block gas limit is reached, have no receipt yet
+	if receipt.TransactionHash == "" {
+		return evm.TransactionReceipt{}, fmt.Errorf("not included in block %d, the block gas limit may have been reached", number)
+	}
+
+	blockNumber := evm.ParseQuantity(receipt.BlockNumber)
+	txIndex := evm.ParseQuantity(receipt.TransactionIndex)
+	if blockNumber != number || txIndex != uint64(index) {
+		return evm.TransactionReceipt{}, fmt.Errorf("mined at index %d of block %d instead of index %d of block %d", txIndex, blockNumber, index, number)
+	}
+
+	return receipt, nil
+}
+
+// Block overrides that run a call in the context of the historical block
+func (prefix *blockPrefix) blockOverrides() *evm.BlockOverrides {
+	if prefix == nil {
+		return nil
+	}
+
+	return &evm.BlockOverrides{Number: prefix.block.Number, Time: prefix.block.Timestamp}
+}
+
+func parseBlockNumber(blockNumber string) (uint64, error) {
+	if strings.HasPrefix(blockNumber, "0x") {
+		return strconv.ParseUint(blockNumber[2:], 16, 64)
+	}
+
+	return strconv.ParseUint(blockNumber, 10, 64)
+}
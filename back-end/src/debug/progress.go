@@ -0,0 +1,96 @@
+package debug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Simulation stages
+const (
+	StageForking         = "forking"
+	StageExecuting       = "executing"
+	StageTracing         = "tracing"
+	StageFetchingSources = "fetchingSources"
+	StageCompiling       = "compiling"
+)
+
+// Parts of a simulation result known before the whole simulation is done
+const (
+	PartialContractsCalled = "contractsCalled"
+	PartialLogs            = "logs"
+	PartialRevert          = "revert"
+)
+
+var ErrCancelled = errors.New("simulation cancelled")
+
+// Progress follows a simulation running in the background: its current stage, what it is doing, the
+// results it already has, and the forks it creates so they can be torn down when it is cancelled
+type Progress interface {
+	Context() context.Context // Cancelled when the simulation is
+	SetStage(stage string)
+	Log(message string)
+	SetPartialResult(name string, value interface{})
+	AddFork(forkId string)
+	ReleaseFork(forkId string) bool // Whether the fork is still to be deleted, false once a cancellation did
+}
+
+// Progress of simulations answered within the request
+type noProgress struct{}
+
+func (noProgress) Context() context.Context                        { return context.Background() }
+func (noProgress) SetStage(stage string)                           {}
+func (noProgress) Log(message string)                              {}
+func (noProgress) SetPartialResult(name string, value interface{}) {}
+func (noProgress) AddFork(forkId string)                           {}
+func (noProgress) ReleaseFork(forkId string) bool                  { return true }
+
+// Stops the simulation between two RPC calls when it was cancelled, its forks are already gone
+func checkCancelled(progress Progress) error {
+	if progress.Context().Err() != nil {
+		return ErrCancelled
+	}
+
+	return nil
+}
+
+// Moves to the next stage, or stops the simulation when it was cancelled
+func setStage(progress Progress, stage string) error {
+	err := checkCancelled(progress)
+	if err != nil {
+		return err
+	}
+
+	progress.SetStage(stage)
+	return nil
+}
+
+// Waits for a fork, returning early when the simulation is cancelled
+func pause(progress Progress, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-progress.Context().Done():
+		return ErrCancelled
+	}
+}
+
+// Deletes a fork of the simulation unless its cancellation already did
+func (s *Service) releaseFork(progress Progress, forkId string) error {
+	if !progress.ReleaseFork(forkId) {
+		return nil
+	}
+
+	return s.forkService.DeleteFork(forkId)
+}
+
+// Prints a progress line and passes it on to the progress of the simulation
+func report(progress Progress, icon string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	fmt.Printf("%s %s\n", icon, message)
+	progress.Log(message)
+}
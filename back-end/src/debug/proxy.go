@@ -0,0 +1,140 @@
+package debug
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Proxy standards detected by reading the implementation from the fork
+const (
+	ProxyTypeNone        = ""
+	ProxyTypeEIP1167     = "EIP-1167"
+	ProxyTypeEIP1967     = "EIP-1967"
+	ProxyTypeBeacon      = "EIP-1967 Beacon"
+	ProxyTypeEIP1822     = "EIP-1822"
+	ProxyTypeTransparent = "Transparent"
+)
+
+// Storage slots holding the implementation address
+const (
+	eip1967ImplementationSlot    = "0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc"
+	eip1967BeaconSlot            = "0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50"
+	eip1822ProxiableSlot         = "0xc5f16f0fcc639fa48a6947836d9850f504798523bf8c9a3a87d5876cf622bcf7"
+	zeppelinOSImplementationSlot = "0x7050c9e0f4ca769c69bd3a8ef740bc37934f8e2c036e5a723fd8ee048ed3f8c3"
+)
+
+// implementation() selector, used to query beacons
+const implementationSelector = "0x5c60da1b"
+
+// EIP-1167 runtime code around the 20 byte implementation address
+const (
+	minimalProxyPrefix = "363d3d373d3d3d363d73"
+	minimalProxySuffix = "5af43d82803e903d91602b57fd5bf3"
+)
+
+// GetImplementation returns the implementation behind a proxy, or an empty address if it isn't one.
+// The code and slots are read on every call since forks can upgrade proxies at any time.
+func (s *Service) GetImplementation(forkId string, address string) (ProxyInfo, error) {
+	bytecode, err := s.evmService.GetContractBytecode(forkId, address)
+	if err != nil {
+		return ProxyInfo{}, err
+	}
+
+	code := strings.ToLower(strings.TrimPrefix(bytecode, "0x"))
+	if strings.HasPrefix(code, minimalProxyPrefix) && len(code) >= len(minimalProxyPrefix)+40 {
+		implementation := code[len(minimalProxyPrefix) : len(minimalProxyPrefix)+40]
+		if strings.HasPrefix(code[len(minimalProxyPrefix)+40:], minimalProxySuffix) {
+			return ProxyInfo{Implementation: "0x" + implementation, ProxyType: ProxyTypeEIP1167}, nil
+		}
+	}
+
+	implementation, err := s.readAddressSlot(forkId, address, eip1967ImplementationSlot)
+	if err != nil {
+		return ProxyInfo{}, err
+	}
+	if implementation != "" {
+		return ProxyInfo{Implementation: implementation, ProxyType: ProxyTypeEIP1967}, nil
+	}
+
+	beacon, err := s.readAddressSlot(forkId, address, eip1967BeaconSlot)
+	if err != nil {
+		return ProxyInfo{}, err
+	}
+	if beacon != "" {
+		proxyInfo, err := s.getBeaconImplementation(forkId, beacon)
+		if err != nil {
+			return ProxyInfo{}, err
+		}
+		if proxyInfo.Implementation != "" {
+			return proxyInfo, nil
+		}
+	}
+
+	implementation, err = s.readAddressSlot(forkId, address, eip1822ProxiableSlot)
+	if err != nil {
+		return ProxyInfo{}, err
+	}
+	if implementation != "" {
+		return ProxyInfo{Implementation: implementation, ProxyType: ProxyTypeEIP1822}, nil
+	}
+
+	implementation, err = s.readAddressSlot(forkId, address, zeppelinOSImplementationSlot)
+	if err != nil {
+		return ProxyInfo{}, err
+	}
+	if implementation != "" {
+		return ProxyInfo{Implementation: implementation, ProxyType: ProxyTypeTransparent}, nil
+	}
+
+	return ProxyInfo{}, nil
+}
+
+func (s *Service) getBeaconImplementation(forkId string, beacon string) (ProxyInfo, error) {
+	result, err := s.evmService.SendCallTransaction(forkId, beacon, implementationSelector)
+	if err != nil {
+		return ProxyInfo{}, err
+	}
+
+	implementation := wordToAddress(result)
+	if implementation == "" {
+		return ProxyInfo{}, nil
+	}
+
+	return ProxyInfo{Implementation: implementation, ProxyType: ProxyTypeBeacon, Beacon: beacon}, nil
+}
+
+func (s *Service) readAddressSlot(forkId string, address string, slot string) (string, error) {
+	value, err := s.evmService.GetStorageAt(forkId, address, slot)
+	if err != nil {
+		return "", err
+	}
+
+	return wordToAddress(value), nil
+}
+
+// Address stored in the low 20 bytes of a word, empty for zero
+func wordToAddress(word string) string {
+	value, success := new(big.Int).SetString(strings.TrimPrefix(word, "0x"), 16)
+	if !success || value.Sign() == 0 || value.BitLen() > 160 {
+		return ""
+	}
+
+	return fmt.Sprintf("0x%040x", value)
+}
+
+// getAbiAddresses lists the addresses whose ABI can decode calls to address, implementation first
+func (s *Service) getAbiAddresses(forkId string, address string) []string {
+	proxyInfo, err := s.GetImplementation(forkId, address)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to detect proxy for %s: %v\n", address, err)
+		return []string{address}
+	}
+
+	if proxyInfo.Implementation == "" {
+		return []string{address}
+	}
+
+	// The proxy ABI is still needed for admin functions like upgradeTo
+	return []string{proxyInfo.Implementation, address}
+}
@@ -0,0 +1,175 @@
+package debug
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Revert kinds
+const (
+	RevertError  = "error"  // Error(string)
+	RevertPanic  = "panic"  // Panic(uint256)
+	RevertCustom = "custom" // Custom error declared in the contract
+	RevertEmpty  = "empty"  // Revert without data
+	RevertHalt   = "halt"   // Exceptional halt such as out of gas or an invalid opcode
+)
+
+var (
+	errorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+// Panic codes emitted by the Solidity compiler
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "incorrectly encoded storage byte array",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to uninitialized internal function",
+}
+
+// DecodeRevert finds the frame the revert originated in and decodes its return data.
+// Returns nil when the transaction did not fail.
+func (s *Service) DecodeRevert(forkId string, frames []callFrame) *RevertInfo {
+	if len(frames) == 0 || frames[0].trace.Error == "" {
+		return nil
+	}
+
+	origin := getRevertOrigin(frames)
+	trace := origin.trace
+
+	revert := &RevertInfo{
+		ContractAddress: origin.codeAddress,
+		FrameId:         origin.id,
+		Depth:           origin.depth,
+		Data:            trace.Output,
+	}
+
+	data, err := hex.DecodeString(strings.TrimPrefix(trace.Output, "0x"))
+	if err != nil || len(data) == 0 {
+		revert.Data = "0x"
+		if trace.Error == "execution reverted" {
+			revert.Kind = RevertEmpty
+			revert.Reason = "execution reverted without a reason"
+		} else {
+			revert.Kind = RevertHalt
+			revert.Reason = trace.Error
+		}
+		return revert
+	}
+
+	if len(data) >= 4 {
+		revert.Selector = "0x" + hex.EncodeToString(data[:4])
+	}
+
+	if reason, err := decodeErrorBlob(trace.Output); err == nil {
+		revert.Reason = reason
+		if bytes.HasPrefix(data, panicSelector) {
+			revert.Kind = RevertPanic
+			revert.ErrorName = "Panic"
+			revert.PanicCode = "0x" + new(big.Int).SetBytes(data[4:]).Text(16)
+		} else {
+			revert.Kind = RevertError
+			revert.ErrorName = "Error"
+		}
+		return revert
+	}
+
+	revert.Kind = RevertCustom
+	errorName, arguments, err := s.decodeCustomError(forkId, origin.codeAddress, data)
+	if err != nil {
+		fmt.Printf("⚠️  Could not decode custom error %s of %s: %v\n", revert.Selector, origin.codeAddress, err)
+		revert.Reason = "custom error " + revert.Selector
+		return revert
+	}
+
+	var formattedArguments []string
+	for _, argument := range arguments {
+		formattedArguments = append(formattedArguments, argument.Name+": "+argument.Value)
+	}
+
+	revert.ErrorName = errorName
+	revert.Arguments = arguments
+	revert.Reason = errorName + "(" + strings.Join(formattedArguments, ", ") + ")"
+
+	return revert
+}
+
+// The revert originates in the deepest failed frame whose output bubbled up unchanged to its parent
+func getRevertOrigin(frames []callFrame) callFrame {
+	origin := frames[0]
+
+	for {
+		var next *callFrame
+		for i := range frames {
+			frame := &frames[i]
+			if frame.parentId != origin.id || frame.trace.Error == "" {
+				continue
+			}
+
+			if frame.trace.Output != "" && frame.trace.Output != "0x" && frame.trace.Output == origin.trace.Output {
+				next = frame
+			}
+		}
+
+		if next == nil {
+			return origin
+		}
+		origin = *next
+	}
+}
+
+// Decodes custom errors with the ABI of the reverting contract, or its implementation
+func (s *Service) decodeCustomError(forkId string, address string, data []byte) (string, []Argument, error) {
+	if len(data) < 4 {
+		return "", nil, errors.New("revert data too short for an error selector")
+	}
+
+	abiCache := make(map[string]*abi.ABI)
+	for _, abiAddress := range s.getAbiAddresses(forkId, address) {
+		parsedAbi, err := s.getCachedAbi(abiAddress, abiCache)
+		if err != nil || parsedAbi == nil {
+			continue
+		}
+
+		for _, abiError := range parsedAbi.Errors {
+			if !bytes.Equal(abiError.ID[:4], data[:4]) {
+				continue
+			}
+
+			values, err := abiError.Inputs.Unpack(data[4:])
+			if err != nil {
+				return "", nil, err
+			}
+
+			var arguments []Argument
+			for i, input := range abiError.Inputs {
+				name := input.Name
+				if name == "" {
+					name = fmt.Sprintf("arg%d", i)
+				}
+
+				arguments = append(arguments, Argument{
+					Name:  name,
+					Type:  input.Type.String(),
+					Value: fmt.Sprint(values[i]),
+				})
+			}
+
+			return abiError.Name, arguments, nil
+		}
+	}
+
+	return "", nil, errors.New("error selector not found in ABI")
+}
@@ -1,20 +1,23 @@
 package debug
 
 import (
+	"Simulations/src/balance"
 	"Simulations/src/etherscan"
+	"Simulations/src/localsources"
 	evm "Simulations/src/rpc"
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os/exec"
+	"math/big"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
-
-	"os"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 type forkService interface {
@@ -23,7 +26,8 @@ type forkService interface {
 	DeleteFork(forkId string) error
 }
 
-type etherscanService interface {
+// Verified sources and ABIs, from the explorers configured for the chain
+type sourcesService interface {
 	GetSourceCodeInfo(address string) (etherscan.SourceCodeInfo, error)
 	GetAbi(address string) (string, error)
 }
@@ -32,22 +36,92 @@ type evmService interface {
 	GetContractBytecode(forkId string, contractAddress string) (string, error)
 	GetTransactionTrace(forkId string, txHash string) ([]evm.CallTrace, error)
 	GetOpcodeTrace(forkId string, txHash string) (evm.DebugResult, error)
+	GetOpcodeTraceWithConfig(forkId string, txHash string, config map[string]interface{}) (evm.DebugResult, error)
 	GetTransactionErrorMessage(forkId string, txHash string) (string, error)
+	GetTransactionReceipt(forkId string, txHash string) (evm.TransactionReceipt, error)
+	GetStateDiff(forkId string, txHash string) (evm.PrestateDiff, error)
+	GetStorageAt(forkId string, contractAddress string, slot string) (string, error)
+	SendCallTransaction(forkId, tokenAddress, funcEncoded string) (string, error)
 	SendRpcRequest(forkId string, rawData []byte) (int, []byte, error)
 	MineTx(forkId string) error
+	SetBalance(forkId, userAddress, balance string) (int, error)
+	SetNonce(forkId, address, nonce string) error
+	SetCode(forkId, contractAddress, code string) error
+	ChangeStorageSlot(forkId, tokenAddress, value, slot string) error
+	SendRawTransaction(forkId string, rawTransaction string) (string, error)
+	SendTransaction(forkId string, call evm.CallRequest) (string, error)
+	SetAutomine(forkId string, enabled bool) error
+	SetAutoImpersonate(forkId string, enabled bool) error
+	GetCurrentSnapshot(forkId string) (string, error)
+	RevertState(forkId, snapshot string) error
+	TraceCall(forkId string, call evm.CallRequest, options evm.TraceCallOptions, config map[string]interface{}) (evm.DebugResult, error)
+	TraceCallFrames(forkId string, call evm.CallRequest, options evm.TraceCallOptions) ([]evm.CallTrace, error)
+	TraceCallStateDiff(forkId string, call evm.CallRequest, options evm.TraceCallOptions) (evm.PrestateDiff, error)
+	GetUpstreamBlock(blockNumber uint64) (evm.Block, error)
+	GetUpstreamTransaction(txHash string) (evm.BlockTransaction, error)
+	GetUpstreamTransactionReceipt(txHash string) (evm.TransactionReceipt, error)
+	GetUpstreamCode(contractAddress string) (string, error)
+	SetNextBlockTimestamp(forkId string, timestamp string) error
+	SetCoinbase(forkId string, coinbase string) error
+	SetNextBlockBaseFee(forkId string, baseFee string) error
+}
+
+type tokenService interface {
+	GetTokenMetadata(forkId, tokenAddress string) (balance.TokenMetadata, error)
+}
+
+type signatureService interface {
+	GetEventSignature(topic string) (string, error)
+}
+
+type compilerService interface {
+	GetSolc(compilerVersion string) (string, error)
+	GetVyper(compilerVersion string) (string, error)
 }
 
+type cacheService interface {
+	Read(address string, codeHash string, name string) ([]byte, bool)
+	Write(address string, codeHash string, name string, data []byte) error
+	MarkUnverified(address string, codeHash string) error
+	IsUnverified(address string, codeHash string) bool
+	GetAbi(address string) (string, bool, bool)
+	PutAbi(address string, abi string)
+}
+
+type localSourcesService interface {
+	Get(address string) (localsources.LocalSource, bool)
+}
+
+// Cached files of a contract deployment
+const (
+	sourceCodeInfoFile   = "sourceCodeInfo.json"
+	compiledContractFile = "compiledContract.json"
+)
+
 type Service struct {
 	forkService      forkService
-	etherscanService etherscanService
+	sourcesService   sourcesService
 	evmService       evmService
+	tokenService     tokenService
+	signatureService signatureService
+	cacheService     cacheService
+	compilerService  compilerService
+	localSources     localSourcesService
+	stepSessions     map[string]*stepSession
+	sessionMutex     sync.Mutex
 }
 
-func NewService(forkService forkService, etherscanService etherscanService, evmService evmService) *Service {
+func NewService(forkService forkService, sourcesService sourcesService, evmService evmService, tokenService tokenService, signatureService signatureService, cacheService cacheService, compilerService compilerService, localSources localSourcesService) *Service {
 	return &Service{
 		forkService:      forkService,
-		etherscanService: etherscanService,
+		sourcesService:   sourcesService,
 		evmService:       evmService,
+		tokenService:     tokenService,
+		signatureService: signatureService,
+		cacheService:     cacheService,
+		compilerService:  compilerService,
+		localSources:     localSources,
+		stepSessions:     make(map[string]*stepSession),
 	}
 }
 
@@ -67,7 +141,7 @@ func isTargetOpcode(opcode string) bool {
 	return false
 }
 
-func (s *Service) DebugTransaction(forkId string, txHash string) (int, string, []CallTrace, error) {
+func (s *Service) DebugTransaction(forkId string, txHash string) (DebugResult, error) {
 	fmt.Printf("🔍 DEBUG DebugTransaction called with forkId: %s, txHash: %s\n", forkId, txHash)
 
 	// GET OPCODE TRACE FIRST - before any other API calls
@@ -75,7 +149,7 @@ func (s *Service) DebugTransaction(forkId string, txHash string) (int, string, [
 	debugTrace, err := s.evmService.GetOpcodeTrace(forkId, txHash)
 	if err != nil {
 		fmt.Printf("❌ Failed to get opcode trace: %v\n", err)
-		return -1, "", nil, err
+		return DebugResult{}, err
 	}
 	fmt.Printf("✅ Got opcode trace with %d struct logs\n", len(debugTrace.StructLogs))
 
@@ -84,7 +158,7 @@ func (s *Service) DebugTransaction(forkId string, txHash string) (int, string, [
 	helperForkId, err := s.forkService.CreateFork(1)
 	if err != nil {
 		fmt.Printf("❌ Failed to create helper fork for call trace: %v\n", err)
-		return -1, "", nil, err
+		return DebugResult{}, err
 	}
 	fmt.Printf("🔄 Created helper fork %s for call trace\n", helperForkId)
 
@@ -96,94 +170,56 @@ func (s *Service) DebugTransaction(forkId string, txHash string) (int, string, [
 	trace, err := s.evmService.GetTransactionTrace(helperForkId, txHash)
 	if err != nil {
 		fmt.Printf("❌ Failed to get transaction trace: %v\n", err)
-		return -1, "", nil, err
+		return DebugResult{}, err
 	}
 	fmt.Printf("✅ Got %d trace entries\n", len(trace))
 
 	if len(trace) == 0 {
 		fmt.Printf("❌ No transaction trace found\n")
-		return -1, "", nil, errors.New("no transcation trace")
+		return DebugResult{}, errors.New("no transcation trace")
 	}
 
-	contractMap := make(map[int]ContractEntry)
+	result, err := s.buildDebugResult(forkId, helperForkId, txHash, debugTrace, trace)
 
-	fmt.Printf("🔍 Processing %d trace entries for contracts...\n", len(trace))
-	for i, traceEntry := range trace {
-		fmt.Printf("   [%d/%d] Processing contract: %s\n", i+1, len(trace), traceEntry.To)
-
-		contractBytecode, err := s.evmService.GetContractBytecode(helperForkId, traceEntry.To)
-		if err != nil {
-			fmt.Printf("❌ Failed to get bytecode for %s: %v\n", traceEntry.To, err)
-			return -1, "", nil, err
-		}
-		fmt.Printf("   ✅ Got bytecode (length: %d)\n", len(contractBytecode))
-
-		sourceCodes, err := s.GetSourceCode(traceEntry.To)
-		if err != nil {
-			fmt.Printf("❌ Failed to get source code for %s: %v\n", traceEntry.To, err)
-			return -1, "", nil, err
-		}
-
-		// Check if this is an unverified contract
-		isUnverified := false
-		if len(sourceCodes) == 1 {
-			for filename, content := range sourceCodes {
-				if filename == "unverified.sol" && strings.Contains(content, "No source code available") {
-					isUnverified = true
-					break
-				}
-			}
-		}
+	// Clean up helper fork
+	errDelete := s.forkService.DeleteFork(helperForkId)
+	if errDelete != nil {
+		fmt.Printf("⚠️  Failed to delete helper fork %s: %v\n", helperForkId, errDelete)
+	} else {
+		fmt.Printf("🧹 Cleaned up helper fork %s\n", helperForkId)
+	}
 
-		if isUnverified {
-			fmt.Printf("⚠️  Contract %s is unverified, using placeholder\n", traceEntry.To)
-		} else {
-			fmt.Printf("   ✅ Got source code (%d files)\n", len(sourceCodes))
-		}
+	return result, err
+}
 
-		compiledContract, err := s.GetSourceMappingAndFileNames(traceEntry.To)
-		if err != nil {
-			fmt.Printf("❌ Failed to get source mapping for %s: %v\n", traceEntry.To, err)
-			return -1, "", nil, err
-		}
+// buildDebugResult maps the traces of a transaction to its sources, contract code is read from codeForkId
+func (s *Service) buildDebugResult(forkId string, codeForkId string, txHash string, debugTrace evm.DebugResult, trace []evm.CallTrace) (DebugResult, error) {
+	logs := s.DecodeLogs(forkId, trace[0])
+	fmt.Printf("✅ Decoded %d logs\n", len(logs))
 
-		if isUnverified {
-			fmt.Printf("   ⚠️  Using placeholder source mapping for unverified contract\n")
-		} else {
-			fmt.Printf("   ✅ Got source mapping (%d sources)\n", len(compiledContract.Sources))
-		}
+	fmt.Printf("🔍 Resolving call frames for %d struct logs...\n", len(debugTrace.StructLogs))
+	frames, frameIds := resolveFrames(trace[0], debugTrace.StructLogs)
 
-		depth := traceEntry.Depth + 1
-		contractMap[depth] = ContractEntry{
-			address:               traceEntry.To,
-			bytecode:              contractBytecode,
-			sourceCodes:           sourceCodes,
-			fileNames:             compiledContract.Sources,
-			decompressedSourceMap: decompressSourceMap(compiledContract.Srcmap),
-		}
+	frameContracts, err := s.getFrameContracts(noProgress{}, codeForkId, frames)
+	if err != nil {
+		return DebugResult{}, err
 	}
-	fmt.Printf("✅ Finished processing contracts, got %d entries in contractMap\n", len(contractMap))
+	fmt.Printf("✅ Finished processing contracts for %d frames\n", len(frames))
 
 	fmt.Printf("🔍 Getting transaction error message...\n")
 	revertReason, err := s.evmService.GetTransactionErrorMessage(forkId, txHash)
 	if err != nil {
 		fmt.Printf("❌ Failed to get transaction error message: %v\n", err)
-		return -1, "", nil, err
+		return DebugResult{}, err
 	}
 	fmt.Printf("✅ Got revert reason: '%s'\n", revertReason)
 
-	// Clean up helper fork
-	err = s.forkService.DeleteFork(helperForkId)
-	if err != nil {
-		fmt.Printf("⚠️  Failed to delete helper fork %s: %v\n", helperForkId, err)
-	} else {
-		fmt.Printf("🧹 Cleaned up helper fork %s\n", helperForkId)
-	}
+	revert := s.DecodeRevert(forkId, frames)
 
-	var errorMessage string
-	if revertReason == "0x" || revertReason == "" {
-		errorMessage = "Transaction successful!"
-	} else {
+	errorMessage := "Transaction successful!"
+	if revert != nil {
+		errorMessage = revert.Reason
+	} else if revertReason != "" {
 		errorMessage = revertReason
 	}
 
@@ -191,500 +227,308 @@ func (s *Service) DebugTransaction(forkId string, txHash string) (int, string, [
 	opcodes := debugTrace.StructLogs
 	if len(opcodes) == 0 {
 		fmt.Printf("❌ No opcodes found in debug trace\n")
-		return -1, "", nil, errors.New("no debug trace detected")
+		return DebugResult{}, errors.New("no debug trace detected")
 	}
 	fmt.Printf("✅ Found %d opcodes to process\n", len(opcodes))
-	fmt.Printf("📊 Contract map has %d entries\n", len(contractMap))
 
-	var filteredOpcodes []CallTrace
+	filteredOpcodes, err := s.mapOpcodesToSource(opcodes, frames, frameIds, frameContracts)
+	if err != nil {
+		return DebugResult{}, err
+	}
 
-	for i, structLog := range debugTrace.StructLogs {
-		fmt.Printf("🔍 Processing structLog #%d: Op=%s, PC=%d, Depth=%d\n", i, structLog.Op, structLog.Pc, structLog.Depth)
-		contract := contractMap[structLog.Depth]
+	if len(filteredOpcodes) == 0 {
+		return DebugResult{}, errors.New("no opcodes mapped to source")
+	}
 
-		fmt.Printf(" Contract: %s, Bytecode length: %d\n", contract.address, len(contract.bytecode))
+	result := DebugResult{
+		RevertReason: errorMessage,
+		Revert:       revert,
+		LineNumber:   filteredOpcodes[len(filteredOpcodes)-1].LineNumber,
+		DebugTrace:   filteredOpcodes,
+		Logs:         logs,
+	}
 
-		// Check if this is an unverified contract
-		isUnverified := false
-		if len(contract.sourceCodes) == 1 {
-			for filename, content := range contract.sourceCodes {
-				if filename == "unverified.sol" && strings.Contains(content, "No source code available") {
-					isUnverified = true
-					break
-				}
-			}
-		}
+	return result, nil
+}
 
-		if isUnverified {
-			// For unverified contracts, include only target opcodes with placeholder source info
-			if isTargetOpcode(structLog.Op) {
-				filteredOpcodes = append(
-					filteredOpcodes,
-					CallTrace{
-						Opcode:          structLog.Op,
-						LineNumber:      1,
-						File:            "unverified.sol",
-						ContractAddress: contract.address,
-						Depth:           structLog.Depth,
-					},
-				)
-			}
-			continue
-		}
+func (s *Service) SimulateRawTransaction(rawData []byte, blockNumber string, txIndex *int, stateOverrides StateOverride) (SimulationResult, error) {
+	return s.SimulateRawTransactionWithProgress(noProgress{}, rawData, blockNumber, txIndex, stateOverrides)
+}
 
-		// Always get opcodeNumber, even for unverified contracts
-		opcodeNumber, err := s.getOpcodeNumber(structLog.Pc, contract.bytecode)
-		if err != nil {
-			fmt.Printf("⚠️ Could not find PC %d in bytecode, adding opcode with unknown location\n", structLog.Pc)
-			// Add opcode with placeholder info when PC can't be found
-			if isTargetOpcode(structLog.Op) {
-				filteredOpcodes = append(
-					filteredOpcodes,
-					CallTrace{
-						Opcode:          structLog.Op,
-						LineNumber:      -1, // Unknown line
-						File:            "unknown",
-						ContractAddress: contract.address,
-						Depth:           structLog.Depth,
-					},
-				)
-			}
-			continue
-		}
+// SimulateRawTransactionWithProgress simulates a raw transaction, reporting its stages and forks to progress
+func (s *Service) SimulateRawTransactionWithProgress(progress Progress, rawData []byte, blockNumber string, txIndex *int, stateOverrides StateOverride) (SimulationResult, error) {
+	err := stateOverrides.Validate()
+	if err != nil {
+		return SimulationResult{}, err
+	}
 
-		// Check bounds before accessing decompressedSourceMap
-		if opcodeNumber >= len(contract.decompressedSourceMap) {
-			fmt.Printf("⚠️ Opcode number %d is out of bounds (source map length: %d), adding with unknown location\n",
-				opcodeNumber, len(contract.decompressedSourceMap))
-			if isTargetOpcode(structLog.Op) {
-				filteredOpcodes = append(
-					filteredOpcodes,
-					CallTrace{
-						Opcode:          structLog.Op,
-						LineNumber:      -1,
-						File:            "unknown",
-						ContractAddress: contract.address,
-						Depth:           structLog.Depth,
-					},
-				)
-			}
-			continue
-		}
+	err = setStage(progress, StageForking)
+	if err != nil {
+		return SimulationResult{}, err
+	}
 
-		if isTargetOpcode(structLog.Op) {
-			fileId := contract.decompressedSourceMap[opcodeNumber].FileID
-			jumpType := contract.decompressedSourceMap[opcodeNumber].JumpType
+	// With a transaction index the fork starts at the parent block and the earlier transactions are replayed
+	prefix, err := s.getBlockPrefix(blockNumber, txIndex)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	blockNumber = getForkBlock(blockNumber, prefix)
 
-			if structLog.Op == "JUMP" && jumpType == "-" {
-				fmt.Println("Jump type not supported.")
-				continue
-			}
+	// Create New Fork - use block-specific fork if blockNumber is provided
+	var forkId string
+	if blockNumber != "" {
+		forkId, err = s.forkService.CreateForkAtBlock(1, blockNumber)
+		report(progress, "🔄", "Created main fork %s for simulation at block %s", forkId, blockNumber)
+	} else {
+		forkId, err = s.forkService.CreateFork(1)
+		report(progress, "🔄", "Created main fork %s for simulation at latest block", forkId)
+	}
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	progress.AddFork(forkId)
 
-			if !fileIdValid(fileId, contract.sourceCodes) {
-				fmt.Printf("⚠️  Invalid fileId %s, skipping\n", fileId)
-				continue
-			}
+	// Wait for the fork to start
+	report(progress, "⏳", "Waiting 3 seconds for main fork to start...")
+	err = pause(progress, time.Second*3)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
+	}
+	report(progress, "✅", "Main fork should be ready now")
 
-			// Check bounds for fileNames map
-			if _, exists := contract.fileNames[fileId]; !exists {
-				fmt.Printf("⚠️  FileId %s not found in fileNames map, skipping\n", fileId)
-				continue
-			}
+	overrides, patches, err := s.resolveStateOverrides(forkId, stateOverrides)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
+	}
 
-			bytesOffset := contract.decompressedSourceMap[opcodeNumber].Offset
-			fileName := contract.fileNames[fileId]
+	err = setStage(progress, StageExecuting)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
+	}
 
-			// Check if source code exists for this file
-			sourceCode, exists := contract.sourceCodes[fileName]
-			if !exists {
-				fmt.Printf("⚠️  Source code not found for file %s, skipping\n", fileName)
-				continue
-			}
+	var replayedHashes []string
+	if prefix != nil {
+		replayedHashes, err = s.replayBlockPrefix(forkId, prefix)
+		if err != nil {
+			s.releaseFork(progress, forkId)
+			return SimulationResult{}, err
+		}
 
-			lineNumber, err := getLineNumber([]byte(sourceCode), bytesOffset)
+		// Overrides must not change the replayed transactions, they only apply to the simulated one
+		if len(overrides) > 0 {
+			err = s.minePrefix(forkId, prefix, replayedHashes)
 			if err != nil {
-				fmt.Println("NOOOOOO", err)
-				return -1, "", nil, err
-			}
-
-			if len(filteredOpcodes) != 0 && filteredOpcodes[len(filteredOpcodes)-1].LineNumber == lineNumber && structLog.Op != "RETURN" {
-				continue
+				s.releaseFork(progress, forkId)
+				return SimulationResult{}, err
 			}
-
-			filteredOpcodes = append(
-				filteredOpcodes,
-				CallTrace{
-					Opcode:          structLog.Op,
-					LineNumber:      lineNumber,
-					File:            fileName,
-					ContractAddress: contract.address,
-					Depth:           structLog.Depth,
-				},
-			)
 		}
 	}
 
-	return filteredOpcodes[len(filteredOpcodes)-1].LineNumber, errorMessage, filteredOpcodes, nil
-}
-
-func (s *Service) SimulateRawTransaction(rawData []byte, blockNumber string) ([]ContractCalled, int, string, []CallTrace, error) {
-	fmt.Printf("🔍 DEBUG SimulateRawTransaction called with blockNumber: %s\n", blockNumber)
-
-	// Create New Fork - use block-specific fork if blockNumber is provided
-	var forkId string
-	var err error
-	if blockNumber != "" {
-		forkId, err = s.forkService.CreateForkAtBlock(1, blockNumber)
-		fmt.Printf("🔄 Created main fork %s for simulation at block %s\n", forkId, blockNumber)
-	} else {
-		forkId, err = s.forkService.CreateFork(1)
-		fmt.Printf("🔄 Created main fork %s for simulation at latest block\n", forkId)
-	}
+	err = checkCancelled(progress)
 	if err != nil {
-		return nil, 0, "", nil, err
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
 	}
 
-	// Wait for the fork to start
-	fmt.Printf("⏳ Waiting 3 seconds for main fork to start...\n")
-	time.Sleep(time.Second * 3)
-	fmt.Printf("✅ Main fork should be ready now\n")
+	err = s.applyStateOverrides(forkId, overrides)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
+	}
 
 	// Send the rpc request
 	_, resData, err := s.evmService.SendRpcRequest(forkId, rawData)
 	if err != nil {
-		s.forkService.DeleteFork(forkId)
-		return nil, 0, "", nil, err
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
 	}
 
 	// Decode data
 	var res evm.RPCResponse
 	errDecode := json.Unmarshal(resData, &res)
 	if errDecode != nil {
-		s.forkService.DeleteFork(forkId)
-		return nil, 0, "", nil, errDecode
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, errDecode
+	}
+
+	err = checkCancelled(progress)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
 	}
 
 	// Mine the transaction
 	errMine := s.evmService.MineTx(forkId)
 	if errMine != nil {
-		s.forkService.DeleteFork(forkId)
-		return nil, 0, "", nil, errMine
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, errMine
 	}
 
 	// Get the tx hash
 	txHash := res.Result
-	fmt.Printf("✅ Transaction mined with hash: %s\n", txHash)
+	report(progress, "✅", "Transaction mined with hash: %s", txHash)
 
 	// Wait for transaction to be properly indexed after mining
-	fmt.Printf("⏳ Waiting 2 seconds for transaction to be indexed...\n")
-	time.Sleep(2 * time.Second)
-	fmt.Printf("✅ Transaction should be indexed now\n")
+	report(progress, "⏳", "Waiting 2 seconds for transaction to be indexed...")
+	err = pause(progress, 2*time.Second)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
+	}
+	report(progress, "✅", "Transaction should be indexed now")
+
+	// The result only describes the requested position if the whole block was mined in order
+	if prefix != nil {
+		err = s.checkBlockPrefix(forkId, prefix, replayedHashes)
+		if err == nil {
+			number, index := prefix.nextPosition()
+			_, err = s.checkTransactionPosition(forkId, txHash, number, index)
+		}
+		if err != nil {
+			s.releaseFork(progress, forkId)
+			return SimulationResult{}, err
+		}
+	}
+
+	err = setStage(progress, StageTracing)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
+	}
 
 	// GET OPCODE TRACE FIRST - before any other API calls (same as DebugTransaction)
-	fmt.Printf("🔍 Getting opcode trace FIRST...\n")
+	report(progress, "🔍", "Getting opcode trace FIRST...")
 	debugTrace, err := s.evmService.GetOpcodeTrace(forkId, txHash)
 	if err != nil {
-		fmt.Printf("❌ Failed to get opcode trace: %v\n", err)
-		s.forkService.DeleteFork(forkId)
-		return nil, -1, "", nil, err
+		report(progress, "❌", "Failed to get opcode trace: %v", err)
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
+	}
+	report(progress, "✅", "Got opcode trace with %d struct logs", len(debugTrace.StructLogs))
+
+	err = checkCancelled(progress)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
 	}
-	fmt.Printf("✅ Got opcode trace with %d struct logs\n", len(debugTrace.StructLogs))
 
 	// WORKAROUND: Create a new fork for call trace due to Alchemy bug
 	// where debug_traceTransaction corrupts fork state for subsequent calls
 	var helperForkId string
 	if blockNumber != "" {
 		helperForkId, err = s.forkService.CreateForkAtBlock(1, blockNumber)
-		fmt.Printf("🔄 Created helper fork %s for call trace at block %s\n", helperForkId, blockNumber)
+		report(progress, "🔄", "Created helper fork %s for call trace at block %s", helperForkId, blockNumber)
 	} else {
 		helperForkId, err = s.forkService.CreateFork(1)
-		fmt.Printf("🔄 Created helper fork %s for call trace at latest block\n", helperForkId)
+		report(progress, "🔄", "Created helper fork %s for call trace at latest block", helperForkId)
 	}
 	if err != nil {
-		fmt.Printf("❌ Failed to create helper fork for call trace: %v\n", err)
-		s.forkService.DeleteFork(forkId)
-		return nil, -1, "", nil, err
+		report(progress, "❌", "Failed to create helper fork for call trace: %v", err)
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
 	}
+	progress.AddFork(helperForkId)
 
 	// Wait for Anvil to start up
-	fmt.Printf("⏳ Waiting 3 seconds for Anvil to start...\n")
-	time.Sleep(3 * time.Second)
-	fmt.Printf("✅ Anvil should be ready now\n")
+	report(progress, "⏳", "Waiting 3 seconds for Anvil to start...")
+	err = pause(progress, 3*time.Second)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		s.releaseFork(progress, helperForkId)
+		return SimulationResult{}, err
+	}
+	report(progress, "✅", "Anvil should be ready now")
 
 	trace, err := s.evmService.GetTransactionTrace(helperForkId, txHash)
 	if err != nil {
-		fmt.Printf("❌ Failed to get transaction trace: %v\n", err)
-		s.forkService.DeleteFork(forkId)
-		s.forkService.DeleteFork(helperForkId)
-		return nil, -1, "", nil, err
+		report(progress, "❌", "Failed to get transaction trace: %v", err)
+		s.releaseFork(progress, forkId)
+		s.releaseFork(progress, helperForkId)
+		return SimulationResult{}, err
 	}
-	fmt.Printf("✅ Got %d trace entries\n", len(trace))
+	report(progress, "✅", "Got %d trace entries", len(trace))
 
 	if len(trace) == 0 {
-		fmt.Printf("❌ No transaction trace found\n")
-		s.forkService.DeleteFork(forkId)
-		s.forkService.DeleteFork(helperForkId)
-		return nil, -1, "", nil, errors.New("no transcation trace")
+		report(progress, "❌", "No transaction trace found")
+		s.releaseFork(progress, forkId)
+		s.releaseFork(progress, helperForkId)
+		return SimulationResult{}, errors.New("no transcation trace")
 	}
 
 	// Get contracts called from trace
-	var contractsCalled []ContractCalled
-	for _, traceEntry := range trace {
-		method, params, err := s.getMethodAndParams(traceEntry.To, traceEntry.Input)
-		if err != nil {
-			contractsCalled = append(contractsCalled, ContractCalled{
-				ContractAddress:   traceEntry.To,
-				CallType:          traceEntry.Type,
-				FunctionSignature: "Unknown",
-				Arguments:         nil,
-			})
-			continue
-		}
+	contractsCalled := s.getContractsCalledFromTrace(forkId, trace)
+	progress.SetPartialResult(PartialContractsCalled, contractsCalled)
 
-		var arguments []Argument
-		for i, param := range params {
-			arguments = append(arguments, Argument{
-				Name:  method.Inputs[i].Name,
-				Type:  method.Inputs[i].Type.String(),
-				Value: fmt.Sprint(param),
-			})
-		}
-
-		contractsCalled = append(contractsCalled, ContractCalled{
-			ContractAddress:   traceEntry.To,
-			CallType:          traceEntry.Type,
-			FunctionSignature: method.String(),
-			Arguments:         arguments,
-		})
-	}
+	logs := s.DecodeLogs(forkId, trace[0])
+	report(progress, "✅", "Decoded %d logs", len(logs))
+	progress.SetPartialResult(PartialLogs, logs)
 
-	contractMap := make(map[int]ContractEntry)
+	report(progress, "🔍", "Resolving call frames for %d struct logs...", len(debugTrace.StructLogs))
+	frames, frameIds := resolveFrames(trace[0], debugTrace.StructLogs)
 
-	fmt.Printf("🔍 Processing %d trace entries for contracts...\n", len(trace))
-	for i, traceEntry := range trace {
-		fmt.Printf("   [%d/%d] Processing contract: %s\n", i+1, len(trace), traceEntry.To)
-
-		contractBytecode, err := s.evmService.GetContractBytecode(helperForkId, traceEntry.To)
-		if err != nil {
-			fmt.Printf("❌ Failed to get bytecode for %s: %v\n", traceEntry.To, err)
-			s.forkService.DeleteFork(forkId)
-			s.forkService.DeleteFork(helperForkId)
-			return nil, -1, "", nil, err
-		}
-		fmt.Printf("   ✅ Got bytecode (length: %d)\n", len(contractBytecode))
-
-		sourceCodes, err := s.GetSourceCode(traceEntry.To)
-		if err != nil {
-			fmt.Printf("❌ Failed to get source code for %s: %v\n", traceEntry.To, err)
-			s.forkService.DeleteFork(forkId)
-			s.forkService.DeleteFork(helperForkId)
-			return nil, -1, "", nil, err
-		}
-
-		// Check if this is an unverified contract
-		isUnverified := false
-		if len(sourceCodes) == 1 {
-			for filename, content := range sourceCodes {
-				if filename == "unverified.sol" && strings.Contains(content, "No source code available") {
-					isUnverified = true
-					break
-				}
-			}
-		}
-
-		if isUnverified {
-			fmt.Printf("⚠️  Contract %s is unverified, using placeholder\n", traceEntry.To)
-		} else {
-			fmt.Printf("   ✅ Got source code (%d files)\n", len(sourceCodes))
-		}
-
-		compiledContract, err := s.GetSourceMappingAndFileNames(traceEntry.To)
-		if err != nil {
-			fmt.Printf("❌ Failed to get source mapping for %s: %v\n", traceEntry.To, err)
-			s.forkService.DeleteFork(forkId)
-			s.forkService.DeleteFork(helperForkId)
-			return nil, -1, "", nil, err
-		}
+	// The revert only needs the call frames, so it is known before the sources are compiled
+	revert := s.DecodeRevert(forkId, frames)
+	if revert != nil {
+		progress.SetPartialResult(PartialRevert, revert)
+	}
 
-		if isUnverified {
-			fmt.Printf("   ⚠️  Using placeholder source mapping for unverified contract\n")
-		} else {
-			fmt.Printf("   ✅ Got source mapping (%d sources)\n", len(compiledContract.Sources))
-		}
+	frameContracts, err := s.getFrameContracts(progress, helperForkId, frames)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		s.releaseFork(progress, helperForkId)
+		return SimulationResult{}, err
+	}
+	useOverriddenCode(frameContracts, frames, overrides, patches)
+	report(progress, "✅", "Finished processing contracts for %d frames", len(frames))
 
-		depth := traceEntry.Depth + 1
-		contractMap[depth] = ContractEntry{
-			address:               traceEntry.To,
-			bytecode:              contractBytecode,
-			sourceCodes:           sourceCodes,
-			fileNames:             compiledContract.Sources,
-			decompressedSourceMap: decompressSourceMap(compiledContract.Srcmap),
-		}
+	err = checkCancelled(progress)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		s.releaseFork(progress, helperForkId)
+		return SimulationResult{}, err
 	}
-	fmt.Printf("✅ Finished processing contracts, got %d entries in contractMap\n", len(contractMap))
 
-	fmt.Printf("🔍 Getting transaction error message...\n")
+	report(progress, "🔍", "Getting transaction error message...")
 	revertReason, err := s.evmService.GetTransactionErrorMessage(forkId, txHash)
 	if err != nil {
-		fmt.Printf("❌ Failed to get transaction error message: %v\n", err)
-		s.forkService.DeleteFork(forkId)
-		s.forkService.DeleteFork(helperForkId)
-		return nil, -1, "", nil, err
+		report(progress, "❌", "Failed to get transaction error message: %v", err)
+		s.releaseFork(progress, forkId)
+		s.releaseFork(progress, helperForkId)
+		return SimulationResult{}, err
 	}
-	fmt.Printf("✅ Got revert reason: '%s'\n", revertReason)
+	report(progress, "✅", "Got revert reason: '%s'", revertReason)
 
 	// Clean up helper fork
-	err = s.forkService.DeleteFork(helperForkId)
+	err = s.releaseFork(progress, helperForkId)
 	if err != nil {
-		fmt.Printf("⚠️  Failed to delete helper fork %s: %v\n", helperForkId, err)
+		report(progress, "⚠️", "Failed to delete helper fork %s: %v", helperForkId, err)
 	} else {
-		fmt.Printf("🧹 Cleaned up helper fork %s\n", helperForkId)
+		report(progress, "🧹", "Cleaned up helper fork %s", helperForkId)
 	}
 
-	var errorMessage string
-	if revertReason == "0x" || revertReason == "" {
-		errorMessage = "Transaction successful!"
-	} else {
+	errorMessage := "Transaction successful!"
+	if revert != nil {
+		errorMessage = revert.Reason
+	} else if revertReason != "" {
 		errorMessage = revertReason
 	}
 
-	fmt.Printf("🔍 Processing opcodes for debugging...\n")
+	report(progress, "🔍", "Processing opcodes for debugging...")
 	opcodes := debugTrace.StructLogs
 	if len(opcodes) == 0 {
-		fmt.Printf("❌ No opcodes found in debug trace\n")
-		s.forkService.DeleteFork(forkId)
-		return nil, -1, "", nil, errors.New("no debug trace detected")
+		report(progress, "❌", "No opcodes found in debug trace")
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, errors.New("no debug trace detected")
 	}
-	fmt.Printf("✅ Found %d opcodes to process\n", len(opcodes))
-	fmt.Printf("📊 Contract map has %d entries\n", len(contractMap))
-
-	var filteredOpcodes []CallTrace
-
-	for i, structLog := range debugTrace.StructLogs {
-		fmt.Printf("🔍 Processing structLog #%d: Op=%s, PC=%d, Depth=%d\n", i, structLog.Op, structLog.Pc, structLog.Depth)
-		contract := contractMap[structLog.Depth]
-
-		fmt.Printf(" Contract: %s, Bytecode length: %d\n", contract.address, len(contract.bytecode))
-
-		// Check if this is an unverified contract
-		isUnverified := false
-		if len(contract.sourceCodes) == 1 {
-			for filename, content := range contract.sourceCodes {
-				if filename == "unverified.sol" && strings.Contains(content, "No source code available") {
-					isUnverified = true
-					break
-				}
-			}
-		}
-
-		if isUnverified {
-			// For unverified contracts, include only target opcodes with placeholder source info
-			if isTargetOpcode(structLog.Op) {
-				filteredOpcodes = append(
-					filteredOpcodes,
-					CallTrace{
-						Opcode:          structLog.Op,
-						LineNumber:      1,
-						File:            "unverified.sol",
-						ContractAddress: contract.address,
-						Depth:           structLog.Depth,
-					},
-				)
-			}
-			continue
-		}
-
-		// Always get opcodeNumber, even for unverified contracts
-		opcodeNumber, err := s.getOpcodeNumber(structLog.Pc, contract.bytecode)
-		if err != nil {
-			fmt.Printf("⚠️ Could not find PC %d in bytecode, adding opcode with unknown location\n", structLog.Pc)
-			// Add opcode with placeholder info when PC can't be found
-			if isTargetOpcode(structLog.Op) {
-				filteredOpcodes = append(
-					filteredOpcodes,
-					CallTrace{
-						Opcode:          structLog.Op,
-						LineNumber:      -1, // Unknown line
-						File:            "unknown",
-						ContractAddress: contract.address,
-						Depth:           structLog.Depth,
-					},
-				)
-			}
-			continue
-		}
-
-		// Check bounds before accessing decompressedSourceMap
-		if opcodeNumber >= len(contract.decompressedSourceMap) {
-			fmt.Printf("⚠️ Opcode number %d is out of bounds (source map length: %d), adding with unknown location\n",
-				opcodeNumber, len(contract.decompressedSourceMap))
-			if isTargetOpcode(structLog.Op) {
-				filteredOpcodes = append(
-					filteredOpcodes,
-					CallTrace{
-						Opcode:          structLog.Op,
-						LineNumber:      -1,
-						File:            "unknown",
-						ContractAddress: contract.address,
-						Depth:           structLog.Depth,
-					},
-				)
-			}
-			continue
-		}
-
-		if isTargetOpcode(structLog.Op) {
-			fileId := contract.decompressedSourceMap[opcodeNumber].FileID
-			jumpType := contract.decompressedSourceMap[opcodeNumber].JumpType
-
-			if structLog.Op == "JUMP" && jumpType == "-" {
-				fmt.Println("Jump type not supported.")
-				continue
-			}
-
-			if !fileIdValid(fileId, contract.sourceCodes) {
-				fmt.Printf("⚠️  Invalid fileId %s, skipping\n", fileId)
-				continue
-			}
-
-			// Check bounds for fileNames map
-			if _, exists := contract.fileNames[fileId]; !exists {
-				fmt.Printf("⚠️  FileId %s not found in fileNames map, skipping\n", fileId)
-				continue
-			}
-
-			bytesOffset := contract.decompressedSourceMap[opcodeNumber].Offset
-			fileName := contract.fileNames[fileId]
+	report(progress, "✅", "Found %d opcodes to process", len(opcodes))
 
-			// Check if source code exists for this file
-			sourceCode, exists := contract.sourceCodes[fileName]
-			if !exists {
-				fmt.Printf("⚠️  Source code not found for file %s, skipping\n", fileName)
-				continue
-			}
-
-			lineNumber, err := getLineNumber([]byte(sourceCode), bytesOffset)
-			if err != nil {
-				fmt.Println("NOOOOOO", err)
-				s.forkService.DeleteFork(forkId)
-				return nil, -1, "", nil, err
-			}
-
-			if len(filteredOpcodes) != 0 && filteredOpcodes[len(filteredOpcodes)-1].LineNumber == lineNumber && structLog.Op != "RETURN" {
-				continue
-			}
-
-			filteredOpcodes = append(
-				filteredOpcodes,
-				CallTrace{
-					Opcode:          structLog.Op,
-					LineNumber:      lineNumber,
-					File:            fileName,
-					ContractAddress: contract.address,
-					Depth:           structLog.Depth,
-				},
-			)
-		}
+	filteredOpcodes, err := s.mapOpcodesToSource(opcodes, frames, frameIds, frameContracts)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
 	}
 
 	errorLineNumber := -1
@@ -692,15 +536,39 @@ func (s *Service) SimulateRawTransaction(rawData []byte, blockNumber string) ([]
 		errorLineNumber = filteredOpcodes[len(filteredOpcodes)-1].LineNumber
 	}
 
+	err = checkCancelled(progress)
+	if err != nil {
+		s.releaseFork(progress, forkId)
+		return SimulationResult{}, err
+	}
+
+	report(progress, "🔍", "Getting state diff...")
+	stateDiff, err := s.GetStateDiff(forkId, txHash)
+	if err != nil {
+		report(progress, "⚠️", "Failed to get state diff: %v", err)
+	} else {
+		report(progress, "✅", "Got state changes for %d addresses", len(stateDiff))
+	}
+
 	// Deactivate the main fork
-	errDelete := s.forkService.DeleteFork(forkId)
+	errDelete := s.releaseFork(progress, forkId)
 	if errDelete != nil {
-		fmt.Printf("⚠️  Failed to delete main fork %s: %v\n", forkId, errDelete)
+		report(progress, "⚠️", "Failed to delete main fork %s: %v", forkId, errDelete)
 	} else {
-		fmt.Printf("🧹 Cleaned up main fork %s\n", forkId)
+		report(progress, "🧹", "Cleaned up main fork %s", forkId)
 	}
 
-	return contractsCalled, errorLineNumber, errorMessage, filteredOpcodes, nil
+	result := SimulationResult{
+		ContractsCalled: contractsCalled,
+		LineNumber:      errorLineNumber,
+		RevertReason:    errorMessage,
+		Revert:          revert,
+		DebugTrace:      filteredOpcodes,
+		StateDiff:       stateDiff,
+		Logs:            logs,
+	}
+
+	return result, nil
 }
 
 func (s *Service) GetLastAddressCalled(forkId string, txHash string) (string, error) {
@@ -764,6 +632,135 @@ func (s *Service) getOpcodeNumber(opcodePC int, contractBytecode string) (int, e
 	return 0, errors.New("couldn't find the instruction number")
 }
 
+// getContractEntry collects everything needed to map the opcodes of a contract to its source
+func (s *Service) getContractEntry(progress Progress, forkId string, address string) (ContractEntry, error) {
+	contractBytecode, err := s.evmService.GetContractBytecode(forkId, address)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	err = setStage(progress, StageFetchingSources)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	codeHash := getCodeHash(contractBytecode)
+	sourceCodes, err := s.getSourceCode(address, codeHash)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	err = setStage(progress, StageCompiling)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	compiledContract, err := s.GetSourceMappingAndFileNames(address, contractBytecode)
+	if err != nil {
+		return ContractEntry{}, err
+	}
+
+	contract := ContractEntry{
+		address:               address,
+		bytecode:              contractBytecode,
+		sourceCodes:           sourceCodes,
+		fileNames:             compiledContract.Sources,
+		decompressedSourceMap: decompressSourceMap(compiledContract.Srcmap),
+		instructionIndexes:    getInstructionIndexes(contractBytecode),
+		asts:                  compiledContract.Asts,
+	}
+
+	return contract, nil
+}
+
+// getSourceLocation maps a program counter of a contract to a source range and line
+func (s *Service) getSourceLocation(contract ContractEntry, pc int) (SourceLocation, bool) {
+	if isUnverifiedContract(contract.sourceCodes) {
+		return SourceLocation{}, false
+	}
+
+	opcodeNumber, exists := contract.instructionIndexes[pc]
+	if !exists || opcodeNumber >= len(contract.decompressedSourceMap) {
+		return SourceLocation{}, false
+	}
+
+	sourceMapEntry := contract.decompressedSourceMap[opcodeNumber]
+	if !fileIdValid(sourceMapEntry.FileID, contract.sourceCodes) {
+		return SourceLocation{}, false
+	}
+
+	fileName, exists := contract.fileNames[sourceMapEntry.FileID]
+	if !exists {
+		return SourceLocation{}, false
+	}
+
+	sourceCode, exists := contract.sourceCodes[fileName]
+	if !exists {
+		return SourceLocation{}, false
+	}
+
+	lineNumber, err := getLineNumber([]byte(sourceCode), sourceMapEntry.Offset)
+	if err != nil {
+		return SourceLocation{}, false
+	}
+
+	offset, _ := strconv.Atoi(sourceMapEntry.Offset)
+	length, _ := strconv.Atoi(sourceMapEntry.Length)
+
+	location := SourceLocation{
+		File:       fileName,
+		Offset:     offset,
+		Length:     length,
+		LineNumber: lineNumber,
+		JumpType:   sourceMapEntry.JumpType,
+	}
+
+	return location, true
+}
+
+// getTraces returns the opcode trace and the flattened call trace of a transaction
+func (s *Service) getTraces(forkId string, txHash string, opcodeConfig map[string]interface{}) (evm.DebugResult, []evm.CallTrace, error) {
+	debugTrace, err := s.evmService.GetOpcodeTraceWithConfig(forkId, txHash, opcodeConfig)
+	if err != nil {
+		return evm.DebugResult{}, nil, err
+	}
+
+	if len(debugTrace.StructLogs) == 0 {
+		return evm.DebugResult{}, nil, errors.New("no debug trace detected")
+	}
+
+	// WORKAROUND: Create a new fork for call trace due to Alchemy bug
+	// where debug_traceTransaction corrupts fork state for subsequent calls
+	helperForkId, err := s.forkService.CreateFork(1)
+	if err != nil {
+		return evm.DebugResult{}, nil, err
+	}
+	defer s.forkService.DeleteFork(helperForkId)
+
+	// Wait for Anvil to start up
+	time.Sleep(3 * time.Second)
+
+	trace, err := s.evmService.GetTransactionTrace(helperForkId, txHash)
+	if err != nil {
+		return evm.DebugResult{}, nil, err
+	}
+
+	if len(trace) == 0 {
+		return evm.DebugResult{}, nil, errors.New("no transcation trace")
+	}
+
+	return debugTrace, trace, nil
+}
+
+func isUnverifiedContract(sourceCodes map[string]string) bool {
+	if len(sourceCodes) != 1 {
+		return false
+	}
+
+	content, exists := sourceCodes["unverified.sol"]
+	return exists && strings.Contains(content, "No source code available")
+}
+
 func (s *Service) GetContractsCalled(forkId string, txHash string) ([]ContractCalled, error) {
 	// WORKAROUND: Create a new fork for trace due to Alchemy bug
 	// where debug_traceTransaction corrupts fork state for subsequent calls
@@ -794,17 +791,30 @@ func (s *Service) GetContractsCalled(forkId string, txHash string) ([]ContractCa
 		fmt.Printf("🧹 Cleaned up trace fork %s\n", traceForkId)
 	}
 
+	contractsCalled := s.getContractsCalledFromTrace(forkId, traces)
+
+	return contractsCalled, nil
+}
+
+func (s *Service) getContractsCalledFromTrace(forkId string, trace []evm.CallTrace) []ContractCalled {
 	var contractsCalled []ContractCalled
-	for _, trace := range traces {
-		method, params, err := s.getMethodAndParams(trace.To, trace.Input)
-		if err != nil {
-			contractsCalled = append(contractsCalled, ContractCalled{
-				ContractAddress:   trace.To,
-				CallType:          trace.Type,
-				FunctionSignature: "Unknown",
-				Arguments:         nil,
-			})
 
+	for _, traceEntry := range trace {
+		contractCalled := ContractCalled{
+			ContractAddress:   traceEntry.To,
+			CallType:          traceEntry.Type,
+			FunctionSignature: "Unknown",
+		}
+
+		proxyInfo, err := s.GetImplementation(forkId, traceEntry.To)
+		if err == nil {
+			contractCalled.Implementation = proxyInfo.Implementation
+			contractCalled.ProxyType = proxyInfo.ProxyType
+		}
+
+		method, params, err := s.getMethodAndParams(forkId, traceEntry.To, traceEntry.Input)
+		if err != nil {
+			contractsCalled = append(contractsCalled, contractCalled)
 			continue
 		}
 
@@ -817,19 +827,33 @@ func (s *Service) GetContractsCalled(forkId string, txHash string) ([]ContractCa
 			})
 		}
 
-		contractsCalled = append(contractsCalled, ContractCalled{
-			ContractAddress:   trace.To,
-			CallType:          trace.Type,
-			FunctionSignature: method.String(),
-			Arguments:         arguments,
-		})
+		contractCalled.FunctionSignature = method.String()
+		contractCalled.Arguments = arguments
+		contractsCalled = append(contractsCalled, contractCalled)
 	}
 
-	return contractsCalled, nil
+	return contractsCalled
 }
 
-func (s *Service) getMethodAndParams(contractAddress string, input string) (*abi.Method, []interface{}, error) {
-	fmt.Printf("🔍 DEBUG getMethodAndParams called with:\n")
+// getMethodAndParams decodes calldata, using the implementation ABI when the contract is a proxy
+func (s *Service) getMethodAndParams(forkId string, contractAddress string, input string) (*abi.Method, []interface{}, error) {
+	var err error
+
+	for _, abiAddress := range s.getAbiAddresses(forkId, contractAddress) {
+		var method *abi.Method
+		var params []interface{}
+
+		method, params, err = s.decodeMethodWithAbi(abiAddress, input)
+		if err == nil {
+			return method, params, nil
+		}
+	}
+
+	return nil, nil, err
+}
+
+func (s *Service) decodeMethodWithAbi(contractAddress string, input string) (*abi.Method, []interface{}, error) {
+	fmt.Printf("🔍 DEBUG decodeMethodWithAbi called with:\n")
 	fmt.Printf("   Contract: %s\n", contractAddress)
 	fmt.Printf("   Input: %s\n", input)
 
@@ -839,7 +863,7 @@ func (s *Service) getMethodAndParams(contractAddress string, input string) (*abi
 		return nil, nil, errors.New("input data too short or empty")
 	}
 
-	contractAbi, err := s.etherscanService.GetAbi(contractAddress)
+	contractAbi, err := s.getAbi(contractAddress)
 	if err != nil {
 		fmt.Printf("❌ Failed to get ABI: %v\n", err)
 		return nil, nil, fmt.Errorf("failed to get ABI for %s: %w", contractAddress, err)
@@ -888,227 +912,237 @@ func (s *Service) getMethodAndParams(contractAddress string, input string) (*abi
 	return method, params, nil
 }
 
+// GetSourceCode returns the verified source files of a contract as currently deployed on the forked chain
 func (s *Service) GetSourceCode(address string) (map[string]string, error) {
-	outputDir := "output/sourceCodeInfos/"
-
-	if !fileExists(outputDir+address+".sol") && !fileExists(outputDir+address+".json") {
-		sourceCodeInfo, err := s.etherscanService.GetSourceCodeInfo(address)
-		if err != nil {
-			// Return placeholder for unverified contracts instead of error
-			placeholder := make(map[string]string)
-			placeholder["unverified.sol"] = "// No source code available - contract is not verified"
-			return placeholder, nil
-		}
-
-		err = saveSourceCode(outputDir, sourceCodeInfo, address)
-		if err != nil {
-			return nil, err
-		}
+	if !common.IsHexAddress(address) {
+		return nil, errors.New("invalid address")
+	}
 
-		err = compileContract(outputDir, sourceCodeInfo, address)
-		if err != nil {
-			return nil, err
-		}
+	code, err := s.evmService.GetUpstreamCode(address)
+	if err != nil {
+		return nil, err
 	}
 
-	return readSourceCodeFromFile(outputDir, address)
+	return s.getSourceCode(address, getCodeHash(code))
 }
 
-func (s *Service) GetSourceMappingAndFileNames(address string) (CompiledContract, error) {
-	outputDir := "./output/sourceCodeInfos/"
-
-	if !fileExists(outputDir+address+".sol") && !fileExists(outputDir+address+".json") {
-		sourceCodeInfo, err := s.etherscanService.GetSourceCodeInfo(address)
-		if err != nil {
-			// Return placeholder for unverified contracts instead of error
-			placeholder := CompiledContract{
-				Srcmap:  "",
-				Sources: map[string]string{"0": "unverified.sol"},
-			}
-			return placeholder, nil
-		}
-
-		err = saveSourceCode(outputDir, sourceCodeInfo, address)
-		if err != nil {
-			return CompiledContract{}, err
-		}
+func (s *Service) getSourceCode(address string, codeHash string) (map[string]string, error) {
+	info, verified, err := s.getSourceCodeInfo(address, codeHash)
+	if err != nil {
+		return nil, err
+	}
 
-		err = compileContract(outputDir, sourceCodeInfo, address)
-		if err != nil {
-			return CompiledContract{}, err
-		}
+	if !verified {
+		// Return placeholder for unverified contracts instead of error
+		placeholder := make(map[string]string)
+		placeholder["unverified.sol"] = "// No source code available - contract is not verified"
+		return placeholder, nil
 	}
 
-	return readSourceMappingAndFileIdsFromFile("./output/compiledContracts/", address)
+	return getSourceFiles(info, address)
 }
 
-func compileContract(outputDir string, info etherscan.SourceCodeInfo, address string) error {
-	compilerOutputDir := "output/compiledContracts"
-	solcVersion := info.CompilerVersion
-	solc := "solc/" + solcVersion
+// GetSourceMappingAndFileNames returns the compiled source map of a deployment, compiling it on the first request.
+// The source map is left empty when the recompiled code doesn't match the deployed code.
+func (s *Service) GetSourceMappingAndFileNames(address string, deployedCode string) (CompiledContract, error) {
+	codeHash := getCodeHash(deployedCode)
+	var compiledContract CompiledContract
 
-	// make output directory if it doesn't exist
-	err := os.MkdirAll(compilerOutputDir, os.ModePerm)
-	if err != nil && !os.IsExist(err) {
-		return err
+	if rawData, found := s.cacheService.Read(address, codeHash, compiledContractFile); found {
+		err := json.Unmarshal(rawData, &compiledContract)
+		if err == nil {
+			return compiledContract, nil
+		}
 	}
 
-	if !info.IsStandardJSON {
-		sourceCodeFile := outputDir + address + ".sol"
-		cmd := exec.Command(solc, sourceCodeFile, "-o", compilerOutputDir, "--combined-json", "srcmap-runtime")
+	info, verified, err := s.getSourceCodeInfo(address, codeHash)
+	if err != nil {
+		return CompiledContract{}, err
+	}
 
-		// Use the same EVM version as the original compilation
-		if info.EVMVersion != "" && strings.ToLower(info.EVMVersion) != "default" {
-			cmd.Args = append(cmd.Args, "--evm-version", strings.ToLower(info.EVMVersion))
+	if !verified {
+		// Return placeholder for unverified contracts instead of error
+		placeholder := CompiledContract{
+			Srcmap:  "",
+			Sources: map[string]string{"0": "unverified.sol"},
 		}
+		return placeholder, nil
+	}
 
-		if info.OptimizationUsed == "1" {
-			cmd.Args = append(cmd.Args, "--optimize")
-			if info.Runs != "0" {
-				cmd.Args = append(cmd.Args, "--optimize-runs", info.Runs)
-			}
+	// Nothing is cached when the compilation fails, so the next request tries again
+	var rawData []byte
+	if isVyper(info) {
+		vyper, err := s.compilerService.GetVyper(info.CompilerVersion)
+		if err != nil {
+			return CompiledContract{}, err
 		}
 
-		err := cmd.Run()
+		rawData, err = compileVyperContract(vyper, info, address, deployedCode)
 		if err != nil {
-			return err
+			return CompiledContract{}, err
 		}
-
-		oldPath := compilerOutputDir + "/combined.json"
-		newPath := compilerOutputDir + "/" + address + ".json"
-
-		err = os.Rename(oldPath, newPath)
+	} else {
+		solc, err := s.compilerService.GetSolc(info.CompilerVersion)
 		if err != nil {
-			return err
+			return CompiledContract{}, err
 		}
 
-		err = formatSingleFileOutput(newPath, info.ContractName, address)
+		rawData, err = compileContract(solc, info, address, deployedCode)
 		if err != nil {
-			return err
+			return CompiledContract{}, err
 		}
+	}
 
-	} else {
-		sourceCodeFile := outputDir + address + ".json"
-		outputFilePath := compilerOutputDir + "/" + address + ".json"
-		cmd := exec.Command(solc, "--standard-json", sourceCodeFile, "-o", compilerOutputDir)
+	err = s.cacheService.Write(address, codeHash, compiledContractFile, rawData)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to cache compiled contract %s: %v\n", address, err)
+	}
 
-		// For standard JSON, EVM version should be in the JSON, but add it just in case
-		if info.EVMVersion != "" && strings.ToLower(info.EVMVersion) != "default" {
-			cmd.Args = append(cmd.Args, "--evm-version", strings.ToLower(info.EVMVersion))
-		}
+	err = json.Unmarshal(rawData, &compiledContract)
+	if err != nil {
+		return CompiledContract{}, err
+	}
 
-		outputFile, err := os.Create(outputFilePath)
-		if err != nil {
-			return err
-		}
-		defer outputFile.Close()
+	return compiledContract, nil
+}
 
-		cmd.Stdout = outputFile
-		err = cmd.Run()
-		if err != nil {
-			return err
+// Explorer source info of a deployment, fetched once and cached. verified is false for contracts without
+// a verified source, which are remembered for a while so the explorer isn't asked on every trace.
+// Sources registered locally for the address take precedence over the explorer.
+func (s *Service) getSourceCodeInfo(address string, codeHash string) (etherscan.SourceCodeInfo, bool, error) {
+	if localSource, found := s.localSources.Get(address); found {
+		return localSource.Info, true, nil
+	}
+
+	if s.cacheService.IsUnverified(address, codeHash) {
+		return etherscan.SourceCodeInfo{}, false, nil
+	}
+
+	if rawData, found := s.cacheService.Read(address, codeHash, sourceCodeInfoFile); found {
+		var info etherscan.SourceCodeInfo
+		err := json.Unmarshal(rawData, &info)
+		if err == nil {
+			return info, true, nil
 		}
+	}
 
-		err = formatStandardJSONOutput(outputFilePath, info.ContractName)
+	info, err := s.sourcesService.GetSourceCodeInfo(address)
+	if errors.Is(err, etherscan.ErrNotVerified) {
+		err = s.cacheService.MarkUnverified(address, codeHash)
 		if err != nil {
-			return err
+			fmt.Printf("⚠️  Failed to cache unverified contract %s: %v\n", address, err)
 		}
+		return etherscan.SourceCodeInfo{}, false, nil
 	}
-
-	return nil
-}
-
-func decodeErrorBlob(errorBlob string) (string, error) {
-	if errorBlob == "EVM Revert" {
-		return errorBlob, nil
+	if err != nil {
+		// Explorer failures aren't cached, the contract is only shown as unverified this time
+		fmt.Printf("⚠️  Failed to get source code of %s: %v\n", address, err)
+		return etherscan.SourceCodeInfo{}, false, nil
 	}
 
-	// Decode the revert reason
-	revertReasonBytes, err := hex.DecodeString(errorBlob[2:])
+	rawData, err := json.Marshal(info)
 	if err != nil {
-		return "", err
+		return etherscan.SourceCodeInfo{}, false, err
 	}
 
-	// Get the dynamic data length, the 68 byte represent the dynamic data length in the encoded error
-	dynamicDataLength := revertReasonBytes[67]
-
-	// Extract the dynamic data (error message)
-	errorData := revertReasonBytes[68 : 68+dynamicDataLength]
-
-	// Convert the dynamic data from bytes to string
-	errorMessage := string(errorData)
+	err = s.cacheService.Write(address, codeHash, sourceCodeInfoFile, rawData)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to cache source code of %s: %v\n", address, err)
+	}
 
-	return errorMessage, nil
+	return info, true, nil
 }
 
-func formatSingleFileOutput(filePath string, contractName string, address string) error {
-	rawData, err := readFile(filePath)
+// Compiles the explorer sources with their original settings and returns the CompiledContract JSON
+func compileContract(solc string, info etherscan.SourceCodeInfo, address string, deployedCode string) ([]byte, error) {
+	output, contract, err := compileSolidity(solc, info, address, deployedCode)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var singleCompiledContract SingleCompiledContract
-	json.Unmarshal(rawData, &singleCompiledContract)
+	compiledContract := CompiledContract{
+		Srcmap:   contract.bytecode.SourceMap,
+		Sources:  make(map[string]string),
+		Asts:     make(map[string]json.RawMessage),
+		Contract: contract.fileName + ":" + contract.name,
+	}
 
-	var compiledContract CompiledContract
+	if contract.comparison.Status == VerificationMismatch {
+		fmt.Printf("⚠️  Recompiled %s doesn't match the code deployed at %s, ignoring its source map\n", compiledContract.Contract, address)
+		compiledContract.Srcmap = ""
+		compiledContract.BytecodeMismatch = true
+	}
 
-	for key, value := range singleCompiledContract.Contracts {
-		filePathSplit := strings.Split(key, ":")
-		fileName := filePathSplit[len(filePathSplit)-1]
-		if fileName == contractName {
-			compiledContract.Srcmap = value.Srcmap
+	for fileName, source := range output.Sources {
+		compiledContract.Sources[fmt.Sprintf("%v", source.Id)] = fileName
+		if len(source.Ast) > 0 {
+			compiledContract.Asts[fileName] = source.Ast
 		}
 	}
 
-	compiledContract.Sources = make(map[string]string)
-	compiledContract.Sources["0"] = address + ".sol"
+	return json.MarshalIndent(compiledContract, "", "    ")
+}
 
-	newJson, err := json.MarshalIndent(compiledContract, "", "    ")
+// Compiles the explorer sources and picks the contract matching the deployed code
+func compileSolidity(solc string, info etherscan.SourceCodeInfo, address string, deployedCode string) (compilerOutput, selectedContract, error) {
+	input, err := buildStandardJsonInput(info, address)
 	if err != nil {
-		return err
+		return compilerOutput{}, selectedContract{}, err
 	}
 
-	err = os.WriteFile(filePath, newJson, 0644)
+	output, err := runSolc(solc, input)
 	if err != nil {
-		return err
+		return compilerOutput{}, selectedContract{}, err
 	}
 
-	return nil
-}
-
-func formatStandardJSONOutput(filePath string, contractName string) error {
-	rawData, err := readFile(filePath)
+	contract, err := selectContract(output, info, deployedCode)
 	if err != nil {
-		return err
+		return compilerOutput{}, selectedContract{}, err
 	}
 
-	var jsonCompiledContract JsonCompiledContract
-	json.Unmarshal(rawData, &jsonCompiledContract)
-
-	var compiledContract CompiledContract
+	return output, contract, nil
+}
 
-	for key, value := range jsonCompiledContract.Contracts {
-		filePathSplit := strings.Split(key, "/")
-		fileName := filePathSplit[len(filePathSplit)-1]
-		if fileName == contractName+".sol" {
-			compiledContract.Srcmap = value[contractName].Evm.DeployedBytecode.SourceMap
-		}
+// Decodes the standard Error(string) and Panic(uint256) reverts
+func decodeErrorBlob(errorBlob string) (string, error) {
+	if errorBlob == "EVM Revert" {
+		return errorBlob, nil
 	}
 
-	compiledContract.Sources = make(map[string]string)
-	for key, value := range jsonCompiledContract.Sources {
-		compiledContract.Sources[fmt.Sprintf("%v", value.Id)] = key
+	revertReasonBytes, err := hex.DecodeString(strings.TrimPrefix(errorBlob, "0x"))
+	if err != nil {
+		return "", err
 	}
 
-	newJson, err := json.MarshalIndent(compiledContract, "", "    ")
-	if err != nil {
-		return err
+	if len(revertReasonBytes) < 4 {
+		return "", errors.New("revert data too short for an error selector")
 	}
 
-	err = os.WriteFile(filePath, newJson, 0644)
-	if err != nil {
-		return err
+	switch {
+	case bytes.HasPrefix(revertReasonBytes, errorSelector):
+		stringType, _ := abi.NewType("string", "", nil)
+		values, err := abi.Arguments{{Type: stringType}}.Unpack(revertReasonBytes[4:])
+		if err != nil {
+			return "", err
+		}
+
+		return values[0].(string), nil
+
+	case bytes.HasPrefix(revertReasonBytes, panicSelector):
+		uintType, _ := abi.NewType("uint256", "", nil)
+		values, err := abi.Arguments{{Type: uintType}}.Unpack(revertReasonBytes[4:])
+		if err != nil {
+			return "", err
+		}
+
+		panicCode := values[0].(*big.Int)
+		reason, known := panicReasons[panicCode.Uint64()]
+		if !known || !panicCode.IsUint64() {
+			reason = "unknown panic code"
+		}
+
+		return fmt.Sprintf("Panic(0x%x): %s", panicCode, reason), nil
 	}
 
-	return nil
+	return "", errors.New("not an Error(string) or Panic(uint256) revert")
 }
@@ -0,0 +1,198 @@
+package debug
+
+import (
+	evm "Simulations/src/rpc"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// SimulateCall traces an unsigned call with debug_traceCall on a single fork, no signed transaction needed
+func (s *Service) SimulateCall(simulation CallSimulation) (SimulationResult, error) {
+	call, err := simulation.toCallRequest()
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	// With a transaction index the fork starts at the parent block and the earlier transactions are replayed
+	prefix, err := s.getBlockPrefix(simulation.BlockNumber, simulation.TxIndex)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	blockNumber := getForkBlock(simulation.BlockNumber, prefix)
+
+	var forkId string
+	if blockNumber != "" {
+		forkId, err = s.forkService.CreateForkAtBlock(1, blockNumber)
+		fmt.Printf("🔄 Created fork %s for call simulation at block %s\n", forkId, blockNumber)
+	} else {
+		forkId, err = s.forkService.CreateFork(1)
+		fmt.Printf("🔄 Created fork %s for call simulation at latest block\n", forkId)
+	}
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	defer s.forkService.DeleteFork(forkId)
+
+	// Wait for the fork to start
+	fmt.Printf("⏳ Waiting 3 seconds for fork to start...\n")
+	time.Sleep(time.Second * 3)
+
+	overrides, patches, err := s.resolveStateOverrides(forkId, simulation.StateOverrides)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	// The call runs on top of the replayed transactions, in the context of their block
+	if prefix != nil {
+		replayedHashes, err := s.replayBlockPrefix(forkId, prefix)
+		if err != nil {
+			return SimulationResult{}, err
+		}
+
+		err = s.evmService.MineTx(forkId)
+		if err != nil {
+			return SimulationResult{}, err
+		}
+
+		err = s.checkBlockPrefix(forkId, prefix, replayedHashes)
+		if err != nil {
+			return SimulationResult{}, err
+		}
+	}
+
+	// Overrides are written to the fork as well so code and storage reads match the traced execution.
+	// They come after the replayed transactions, which have to run on the historical state.
+	err = s.applyStateOverrides(forkId, overrides)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	options := evm.TraceCallOptions{StateOverrides: overrides, BlockOverrides: prefix.blockOverrides()}
+
+	debugTrace, err := s.evmService.TraceCall(forkId, call, options, map[string]interface{}{})
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	if len(debugTrace.StructLogs) == 0 {
+		return SimulationResult{}, errors.New("no debug trace detected")
+	}
+	fmt.Printf("✅ Got opcode trace with %d struct logs\n", len(debugTrace.StructLogs))
+
+	trace, err := s.evmService.TraceCallFrames(forkId, call, options)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	if len(trace) == 0 {
+		return SimulationResult{}, errors.New("no transcation trace")
+	}
+
+	contractsCalled := s.getContractsCalledFromTrace(forkId, trace)
+	logs := s.DecodeLogs(forkId, trace[0])
+
+	frames, frameIds := resolveFrames(trace[0], debugTrace.StructLogs)
+
+	frameContracts, err := s.getFrameContracts(noProgress{}, forkId, frames)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	useOverriddenCode(frameContracts, frames, overrides, patches)
+
+	revert := s.DecodeRevert(forkId, frames)
+
+	errorMessage := "Transaction successful!"
+	if revert != nil {
+		errorMessage = revert.Reason
+	}
+
+	filteredOpcodes, err := s.mapOpcodesToSource(debugTrace.StructLogs, frames, frameIds, frameContracts)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	errorLineNumber := -1
+	if len(filteredOpcodes) > 0 {
+		errorLineNumber = filteredOpcodes[len(filteredOpcodes)-1].LineNumber
+	}
+
+	var stateDiff []AddressStateDiff
+	prestateDiff, err := s.evmService.TraceCallStateDiff(forkId, call, options)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to get state diff: %v\n", err)
+	} else {
+		stateDiff = s.buildStateDiff(forkId, prestateDiff, getTraceLogs(trace[0]))
+	}
+
+	result := SimulationResult{
+		ContractsCalled: contractsCalled,
+		LineNumber:      errorLineNumber,
+		RevertReason:    errorMessage,
+		Revert:          revert,
+		DebugTrace:      filteredOpcodes,
+		StateDiff:       stateDiff,
+		Logs:            logs,
+	}
+
+	return result, nil
+}
+
+func (simulation CallSimulation) toCallRequest() (evm.CallRequest, error) {
+	if simulation.To == "" && simulation.Data == "" {
+		return evm.CallRequest{}, errors.New("call requires a target address or creation data")
+	}
+
+	value, err := toQuantity(simulation.Value)
+	if err != nil {
+		return evm.CallRequest{}, fmt.Errorf("invalid value: %w", err)
+	}
+
+	gas, err := toQuantity(simulation.Gas)
+	if err != nil {
+		return evm.CallRequest{}, fmt.Errorf("invalid gas: %w", err)
+	}
+
+	call := evm.CallRequest{
+		From:  simulation.From,
+		To:    simulation.To,
+		Data:  simulation.Data,
+		Value: value,
+		Gas:   gas,
+	}
+
+	return call, nil
+}
+
+// Converts a decimal or hex number into a hex quantity
+func toQuantity(number string) (string, error) {
+	if number == "" || strings.HasPrefix(number, "0x") {
+		return number, nil
+	}
+
+	value, success := new(big.Int).SetString(number, 10)
+	if !success || value.Sign() < 0 {
+		return "", fmt.Errorf("%s is not a valid quantity", number)
+	}
+
+	return "0x" + value.Text(16), nil
+}
+
+// Logs of a call tree in emission order, in the format of receipt logs
+func getTraceLogs(rootFrame evm.CallTrace) []evm.Log {
+	var frameLogs []frameLog
+	collectLogs(rootFrame, 0, &frameLogs)
+
+	var logs []evm.Log
+	for _, frameLog := range frameLogs {
+		logs = append(logs, evm.Log{
+			Address: frameLog.log.Address,
+			Topics:  frameLog.log.Topics,
+			Data:    frameLog.log.Data,
+		})
+	}
+
+	return logs
+}
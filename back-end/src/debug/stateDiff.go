@@ -0,0 +1,181 @@
+package debug
+
+import (
+	"Simulations/src/balance"
+	evm "Simulations/src/rpc"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// keccak256("Transfer(address,address,uint256)")
+const transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+func (s *Service) GetStateDiff(forkId string, txHash string) ([]AddressStateDiff, error) {
+	prestateDiff, err := s.evmService.GetStateDiff(forkId, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := s.evmService.GetTransactionReceipt(forkId, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildStateDiff(forkId, prestateDiff, receipt.Logs), nil
+}
+
+// Groups prestate tracer changes and Transfer events per address
+func (s *Service) buildStateDiff(forkId string, prestateDiff evm.PrestateDiff, logs []evm.Log) []AddressStateDiff {
+	diffs := make(map[string]*AddressStateDiff)
+	getDiff := func(address string) *AddressStateDiff {
+		address = strings.ToLower(address)
+		if _, exists := diffs[address]; !exists {
+			diffs[address] = &AddressStateDiff{Address: address}
+		}
+		return diffs[address]
+	}
+
+	// Native balance and storage changes from the prestate tracer
+	for address, pre := range prestateDiff.Pre {
+		post, existsAfter := prestateDiff.Post[address]
+
+		balanceBefore := parseBigHex(pre.Balance)
+		balanceAfter := balanceBefore
+		if post.Balance != "" {
+			balanceAfter = parseBigHex(post.Balance)
+		} else if !existsAfter {
+			// Account was destroyed by the transaction
+			balanceAfter = new(big.Int)
+		}
+
+		if balanceBefore.Cmp(balanceAfter) != 0 {
+			getDiff(address).Balance = newBalanceChange(balanceBefore, balanceAfter)
+		}
+
+		for slot, before := range pre.Storage {
+			// Slots cleared by the transaction are omitted from the post state
+			after := zeroSlot
+			if value, exists := post.Storage[slot]; exists {
+				after = value
+			}
+
+			if before != after {
+				diff := getDiff(address)
+				diff.StorageChanges = append(diff.StorageChanges, StorageChange{Slot: slot, Before: before, After: after})
+			}
+		}
+	}
+
+	// Accounts created by the transaction only appear in the post state
+	for address, post := range prestateDiff.Post {
+		pre, existsBefore := prestateDiff.Pre[address]
+		if !existsBefore && post.Balance != "" {
+			balanceAfter := parseBigHex(post.Balance)
+			if balanceAfter.Sign() != 0 {
+				getDiff(address).Balance = newBalanceChange(new(big.Int), balanceAfter)
+			}
+		}
+
+		for slot, after := range post.Storage {
+			if _, exists := pre.Storage[slot]; exists {
+				continue
+			}
+
+			diff := getDiff(address)
+			diff.StorageChanges = append(diff.StorageChanges, StorageChange{Slot: slot, Before: zeroSlot, After: after})
+		}
+	}
+
+	// ERC20 balance changes from Transfer events
+	tokenDeltas := make(map[string]map[string]*big.Int)
+	for _, log := range logs {
+		// ERC721 transfers share the topic but index the token id as well
+		if len(log.Topics) != 3 || log.Topics[0] != transferTopic {
+			continue
+		}
+
+		token := strings.ToLower(log.Address)
+		from := topicToAddress(log.Topics[1])
+		to := topicToAddress(log.Topics[2])
+		value := parseBigHex(log.Data)
+
+		if tokenDeltas[token] == nil {
+			tokenDeltas[token] = make(map[string]*big.Int)
+		}
+		addDelta(tokenDeltas[token], from, new(big.Int).Neg(value))
+		addDelta(tokenDeltas[token], to, value)
+	}
+
+	for token, deltas := range tokenDeltas {
+		metadata, err := s.tokenService.GetTokenMetadata(forkId, token)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to get metadata for token %s: %v\n", token, err)
+		}
+
+		for address, delta := range deltas {
+			if delta.Sign() == 0 {
+				continue
+			}
+
+			change := TokenBalanceChange{
+				TokenAddress: token,
+				Symbol:       metadata.Symbol,
+				Decimals:     metadata.Decimals,
+				Delta:        delta.String(),
+			}
+			if metadata.Decimals != nil {
+				change.FormattedDelta = balance.FormatUnits(delta, *metadata.Decimals)
+			}
+
+			diff := getDiff(address)
+			diff.TokenChanges = append(diff.TokenChanges, change)
+		}
+	}
+
+	var stateDiff []AddressStateDiff
+	for _, diff := range diffs {
+		sort.Slice(diff.StorageChanges, func(i, j int) bool { return diff.StorageChanges[i].Slot < diff.StorageChanges[j].Slot })
+		sort.Slice(diff.TokenChanges, func(i, j int) bool { return diff.TokenChanges[i].TokenAddress < diff.TokenChanges[j].TokenAddress })
+		stateDiff = append(stateDiff, *diff)
+	}
+	sort.Slice(stateDiff, func(i, j int) bool { return stateDiff[i].Address < stateDiff[j].Address })
+
+	return stateDiff
+}
+
+const zeroSlot = "0x0000000000000000000000000000000000000000000000000000000000000000"
+
+func newBalanceChange(before *big.Int, after *big.Int) *BalanceChange {
+	return &BalanceChange{
+		Before: before.String(),
+		After:  after.String(),
+		Delta:  new(big.Int).Sub(after, before).String(),
+	}
+}
+
+func addDelta(deltas map[string]*big.Int, address string, delta *big.Int) {
+	if deltas[address] == nil {
+		deltas[address] = new(big.Int)
+	}
+	deltas[address].Add(deltas[address], delta)
+}
+
+func parseBigHex(hexNumber string) *big.Int {
+	number, success := new(big.Int).SetString(strings.TrimPrefix(hexNumber, "0x"), 16)
+	if !success {
+		return new(big.Int)
+	}
+
+	return number
+}
+
+func topicToAddress(topic string) string {
+	topic = strings.TrimPrefix(topic, "0x")
+	if len(topic) < 40 {
+		return "0x" + topic
+	}
+
+	return "0x" + strings.ToLower(topic[len(topic)-40:])
+}
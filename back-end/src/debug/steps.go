@@ -0,0 +1,184 @@
+package debug
+
+import (
+	evm "Simulations/src/rpc"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Step sessions are kept as long as a default fork
+const stepSessionDuration = 30 * time.Minute
+
+const maxStepPageSize = 1000
+
+// CreateStepSession traces a transaction and keeps every step on the server for paginated access
+func (s *Service) CreateStepSession(forkId string, txHash string, includeMemory bool) (StepSessionInfo, error) {
+	opcodeConfig := map[string]interface{}{"enableMemory": includeMemory}
+
+	debugTrace, trace, err := s.getTraces(forkId, txHash, opcodeConfig)
+	if err != nil {
+		return StepSessionInfo{}, err
+	}
+
+	frames, frameIds := resolveFrames(trace[0], debugTrace.StructLogs)
+
+	frameContracts, err := s.getFrameContracts(noProgress{}, forkId, frames)
+	if err != nil {
+		return StepSessionInfo{}, err
+	}
+
+	steps := make([]Step, len(debugTrace.StructLogs))
+	for i, structLog := range debugTrace.StructLogs {
+		frame := frames[frameIds[i]]
+		contract := frameContracts[frame.id]
+
+		step := Step{
+			Index:           i,
+			Pc:              structLog.Pc,
+			Opcode:          structLog.Op,
+			Gas:             structLog.Gas,
+			GasCost:         structLog.GasCost,
+			Depth:           structLog.Depth,
+			FrameId:         frame.id,
+			ContractAddress: frame.codeAddress,
+			StorageAddress:  frame.storageAddress,
+			Stack:           formatStack(structLog.Stack),
+			Memory:          structLog.Memory,
+			Storage:         getTouchedStorage(structLog),
+			Error:           structLog.Error,
+		}
+
+		if location, found := s.getSourceLocation(contract, structLog.Pc); found {
+			step.Source = &location
+		}
+
+		steps[i] = step
+	}
+
+	var frameSummaries []FrameSummary
+	for _, frame := range frames {
+		frameSummaries = append(frameSummaries, FrameSummary{
+			Id:              frame.id,
+			ParentId:        frame.parentId,
+			Depth:           frame.depth,
+			CallType:        frame.callType,
+			ContractAddress: frame.codeAddress,
+			StorageAddress:  frame.storageAddress,
+		})
+	}
+
+	session := &stepSession{
+		info: StepSessionInfo{
+			SessionId:  uuid.New().String(),
+			TxHash:     txHash,
+			ForkId:     forkId,
+			TotalSteps: len(steps),
+			Failed:     debugTrace.Failed,
+			Frames:     frameSummaries,
+		},
+		steps:          steps,
+		structLogs:     debugTrace.StructLogs,
+		frames:         frames,
+		frameIds:       frameIds,
+		frameContracts: frameContracts,
+	}
+
+	s.sessionMutex.Lock()
+	s.stepSessions[session.info.SessionId] = session
+	s.sessionMutex.Unlock()
+
+	time.AfterFunc(stepSessionDuration, func() {
+		s.DeleteStepSession(session.info.SessionId)
+	})
+
+	return session.info, nil
+}
+
+// GetSteps returns up to limit steps starting at cursor, with the cursor of the next page or -1 at the end
+func (s *Service) GetSteps(sessionId string, cursor int, limit int) (StepPage, error) {
+	session, err := s.getStepSession(sessionId)
+	if err != nil {
+		return StepPage{}, err
+	}
+
+	if cursor < 0 || cursor > len(session.steps) {
+		return StepPage{}, fmt.Errorf("cursor %d out of range", cursor)
+	}
+
+	if limit <= 0 || limit > maxStepPageSize {
+		limit = maxStepPageSize
+	}
+
+	end := cursor + limit
+	if end > len(session.steps) {
+		end = len(session.steps)
+	}
+
+	nextCursor := end
+	if end == len(session.steps) {
+		nextCursor = -1
+	}
+
+	page := StepPage{
+		SessionId:  sessionId,
+		Steps:      session.steps[cursor:end],
+		NextCursor: nextCursor,
+		TotalSteps: len(session.steps),
+	}
+
+	return page, nil
+}
+
+func (s *Service) DeleteStepSession(sessionId string) {
+	s.sessionMutex.Lock()
+	delete(s.stepSessions, sessionId)
+	s.sessionMutex.Unlock()
+}
+
+func (s *Service) getStepSession(sessionId string) (*stepSession, error) {
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+
+	session, exists := s.stepSessions[sessionId]
+	if !exists {
+		return nil, errors.New("step session not found")
+	}
+
+	return session, nil
+}
+
+func formatStack(stack []any) []string {
+	formatted := make([]string, len(stack))
+	for i, value := range stack {
+		formatted[i] = fmt.Sprint(value)
+	}
+
+	return formatted
+}
+
+// Slot and value read by SLOAD or written by SSTORE at this step
+func getTouchedStorage(structLog evm.StructLogs) map[string]string {
+	stackSize := len(structLog.Stack)
+
+	switch {
+	case structLog.Op == "SLOAD" && stackSize >= 1:
+		slot := fmt.Sprint(structLog.Stack[stackSize-1])
+		value := structLog.Storage[normalizeSlot(slot)]
+		return map[string]string{slot: value}
+	case structLog.Op == "SSTORE" && stackSize >= 2:
+		slot := fmt.Sprint(structLog.Stack[stackSize-1])
+		value := fmt.Sprint(structLog.Stack[stackSize-2])
+		return map[string]string{slot: value}
+	}
+
+	return nil
+}
+
+// Struct log storage keys are 32 byte hex without prefix
+func normalizeSlot(slot string) string {
+	value := parseBigHex(slot)
+	return fmt.Sprintf("%064x", value)
+}
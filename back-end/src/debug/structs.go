@@ -1,11 +1,19 @@
 package debug
 
+import (
+	evm "Simulations/src/rpc"
+	"encoding/json"
+	"sync"
+)
+
 // Structs used when returning called contracts
 type ContractCalled struct {
 	ContractAddress   string     `json:"contractAddress"`
 	CallType          string     `json:"callType"`
 	FunctionSignature string     `json:"functionSignature"`
 	Arguments         []Argument `json:"arguments"`
+	Implementation    string     `json:"implementation,omitempty"`
+	ProxyType         string     `json:"proxyType,omitempty"`
 }
 
 type Argument struct {
@@ -19,13 +27,49 @@ type CallTrace struct {
 	LineNumber      int
 	File            string
 	ContractAddress string
+	StorageAddress  string // Differs from ContractAddress inside DELEGATECALL frames
+	FrameId         int
 	Depth           int
 }
 
-// SourceMapping struct as stored in output/compiledContracts
+// SourceMapping struct as stored in the compiled contract cache
 type CompiledContract struct {
-	Srcmap  string            `json:"srcmap"`
-	Sources map[string]string `json:"sources"`
+	Srcmap           string                     `json:"srcmap"`
+	Sources          map[string]string          `json:"sources"`
+	Asts             map[string]json.RawMessage `json:"asts,omitempty"`
+	Contract         string                     `json:"contract,omitempty"` // Fully qualified name of the compiled contract
+	BytecodeMismatch bool                       `json:"bytecodeMismatch,omitempty"`
+}
+
+// How recompiled runtime code compares with the deployed code
+const (
+	VerificationExactMatch   = "exact_match"
+	VerificationPartialMatch = "partial_match" // Only the metadata hash differs, source maps can be trusted
+	VerificationMismatch     = "mismatch"
+)
+
+type CodeComparison struct {
+	Status         string      `json:"status"`
+	CompiledLength int         `json:"compiledLength"`
+	DeployedLength int         `json:"deployedLength"`
+	Differences    []ByteRange `json:"differences"`
+	IgnoredRanges  []ByteRange `json:"ignoredRanges"` // Filled at deployment, taken from the deployed code
+}
+
+// Half-open range of runtime code bytes
+type ByteRange struct {
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Verification report of a deployed contract against its explorer source
+type VerificationReport struct {
+	Address         string `json:"address"`
+	CodeHash        string `json:"codeHash"`
+	Contract        string `json:"contract"`
+	CompilerVersion string `json:"compilerVersion"`
+	CodeComparison
 }
 
 // StandardJsonInput struct
@@ -37,31 +81,30 @@ type InputFile struct {
 	Content string `json:"content"`
 }
 
-// Contract compiled from a single file
-type SingleCompiledContract struct {
-	Contracts map[string]SourceMap `json:"contracts"`
-}
-
-type SourceMap struct {
-	Srcmap string `json:"srcmap-runtime"`
+// Standard JSON input of a Vyper contract, interfaces are given either as source or as ABI
+type VyperJsonInput struct {
+	Sources    map[string]InputFile      `json:"sources"`
+	Interfaces map[string]VyperInterface `json:"interfaces"`
+	Settings   struct {
+		EvmVersion string `json:"evmVersion"`
+	} `json:"settings"`
 }
 
-// JsonCompiledContract Contract compiled from a standard input JSON
-type JsonCompiledContract struct {
-	Contracts map[string]map[string]Contract `json:"contracts"`
-	Sources   map[string]Source              `json:"sources"`
+type VyperInterface struct {
+	Content string          `json:"content"`
+	Abi     json.RawMessage `json:"abi"`
 }
 
-type Contract struct {
-	Evm struct {
-		DeployedBytecode struct {
-			SourceMap string `json:"sourceMap"`
-		} `json:"deployedBytecode"`
-	} `json:"evm"`
+// Source map as printed by vyper -f source_map, positions are [lineno, col_offset, end_lineno, end_col_offset]
+type VyperSourceMap struct {
+	PcPosMap  map[string][]*int `json:"pc_pos_map"`
+	PcJumpMap map[string]string `json:"pc_jump_map"`
 }
 
+// Source of a standard JSON output
 type Source struct {
-	Id int `json:"id"`
+	Id  int             `json:"id"`
+	Ast json.RawMessage `json:"ast"`
 }
 
 // SourceMap entry struct
@@ -80,4 +123,288 @@ type ContractEntry struct {
 	sourceCodes           map[string]string
 	fileNames             map[string]string
 	decompressedSourceMap []Opcode
+	instructionIndexes    map[int]int
+	asts                  map[string]json.RawMessage
+}
+
+// Source range an instruction maps to
+type SourceLocation struct {
+	File       string `json:"file"`
+	Offset     int    `json:"offset"`
+	Length     int    `json:"length"`
+	LineNumber int    `json:"lineNumber"`
+	JumpType   string `json:"jumpType"`
+}
+
+// Simulation result returned for a raw transaction
+type SimulationResult struct {
+	ContractsCalled []ContractCalled
+	LineNumber      int
+	RevertReason    string
+	Revert          *RevertInfo
+	DebugTrace      []CallTrace
+	StateDiff       []AddressStateDiff
+	Logs            []DecodedLog
+}
+
+// State changes of a single address caused by a transaction
+type AddressStateDiff struct {
+	Address        string               `json:"address"`
+	Balance        *BalanceChange       `json:"balance,omitempty"`
+	TokenChanges   []TokenBalanceChange `json:"tokenChanges,omitempty"`
+	StorageChanges []StorageChange      `json:"storageChanges,omitempty"`
+}
+
+type BalanceChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+	Delta  string `json:"delta"`
+}
+
+type TokenBalanceChange struct {
+	TokenAddress   string `json:"tokenAddress"`
+	Symbol         string `json:"symbol"`
+	Decimals       *uint8 `json:"decimals,omitempty"`
+	Delta          string `json:"delta"`
+	FormattedDelta string `json:"formattedDelta,omitempty"`
+}
+
+type StorageChange struct {
+	Slot   string `json:"slot"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Decoded revert of a failed transaction and the frame it originated in
+type RevertInfo struct {
+	Reason          string     `json:"reason"`
+	Kind            string     `json:"kind"`
+	ErrorName       string     `json:"errorName,omitempty"`
+	Selector        string     `json:"selector,omitempty"`
+	Arguments       []Argument `json:"arguments,omitempty"`
+	PanicCode       string     `json:"panicCode,omitempty"`
+	ContractAddress string     `json:"contractAddress"`
+	FrameId         int        `json:"frameId"`
+	Depth           int        `json:"depth"`
+	Data            string     `json:"data"`
+}
+
+// Debug result returned for a transaction already on a fork
+type DebugResult struct {
+	RevertReason string
+	Revert       *RevertInfo
+	LineNumber   int
+	DebugTrace   []CallTrace
+	Logs         []DecodedLog
+}
+
+// Log emitted during a transaction, decoded where the event is known
+type DecodedLog struct {
+	Index           int        `json:"index"`
+	ContractAddress string     `json:"contractAddress"`
+	Depth           int        `json:"depth"`
+	EventName       string     `json:"eventName"`
+	EventSignature  string     `json:"eventSignature"`
+	Arguments       []Argument `json:"arguments"`
+	Topics          []string   `json:"topics"`
+	Data            string     `json:"data"`
+}
+
+// Gas profile of a transaction
+type GasProfile struct {
+	TotalGasUsed uint64        `json:"totalGasUsed"`
+	Frames       []FrameGas    `json:"frames"`
+	Functions    []FunctionGas `json:"functions"`
+	Lines        []LineGas     `json:"lines"`
+}
+
+type FrameGas struct {
+	Id              int    `json:"id"`
+	ParentId        int    `json:"parentId"`
+	Depth           int    `json:"depth"`
+	ContractAddress string `json:"contractAddress"`
+	CallType        string `json:"callType"`
+	Function        string `json:"function"`
+	GasLimit        uint64 `json:"gasLimit"`
+	Inclusive       uint64 `json:"inclusive"`
+	Exclusive       uint64 `json:"exclusive"`
+	stack           string
+}
+
+type FunctionGas struct {
+	ContractAddress string `json:"contractAddress"`
+	Function        string `json:"function"`
+	Calls           int    `json:"calls"`
+	Inclusive       uint64 `json:"inclusive"`
+	Exclusive       uint64 `json:"exclusive"`
+}
+
+type LineGas struct {
+	ContractAddress string `json:"contractAddress"`
+	File            string `json:"file"`
+	LineNumber      int    `json:"lineNumber"`
+	Gas             uint64 `json:"gas"`
+	Steps           int    `json:"steps"`
+}
+
+// Implementation behind a proxy contract
+type ProxyInfo struct {
+	Implementation string `json:"implementation"`
+	ProxyType      string `json:"proxyType"`
+	Beacon         string `json:"beacon,omitempty"`
+}
+
+// Single opcode step of a transaction
+type Step struct {
+	Index           int               `json:"index"`
+	Pc              int               `json:"pc"`
+	Opcode          string            `json:"opcode"`
+	Gas             int               `json:"gas"`
+	GasCost         int               `json:"gasCost"`
+	Depth           int               `json:"depth"`
+	FrameId         int               `json:"frameId"`
+	ContractAddress string            `json:"contractAddress"`
+	StorageAddress  string            `json:"storageAddress"`
+	Stack           []string          `json:"stack"`
+	Memory          []string          `json:"memory,omitempty"`
+	Storage         map[string]string `json:"storage,omitempty"`
+	Source          *SourceLocation   `json:"source,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+type FrameSummary struct {
+	Id              int    `json:"id"`
+	ParentId        int    `json:"parentId"`
+	Depth           int    `json:"depth"`
+	CallType        string `json:"callType"`
+	ContractAddress string `json:"contractAddress"`
+	StorageAddress  string `json:"storageAddress"`
+}
+
+type StepSessionInfo struct {
+	SessionId  string         `json:"sessionId"`
+	TxHash     string         `json:"txHash"`
+	ForkId     string         `json:"forkId"`
+	TotalSteps int            `json:"totalSteps"`
+	Failed     bool           `json:"failed"`
+	Frames     []FrameSummary `json:"frames"`
+}
+
+type StepPage struct {
+	SessionId  string `json:"sessionId"`
+	Steps      []Step `json:"steps"`
+	NextCursor int    `json:"nextCursor"`
+	TotalSteps int    `json:"totalSteps"`
+}
+
+// Steps of a traced transaction kept on the server
+type stepSession struct {
+	info           StepSessionInfo
+	steps          []Step
+	structLogs     []evm.StructLogs
+	frames         []callFrame
+	frameIds       []int
+	frameContracts map[int]ContractEntry
+	astIndexes     map[string]*astIndex
+	astMutex       sync.Mutex
+}
+
+// Named Solidity variable decoded at a step
+type Variable struct {
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Location   string      `json:"location,omitempty"`
+	StackIndex int         `json:"stackIndex"`
+	Value      interface{} `json:"value"`
+}
+
+// Variables in scope at a step
+type StepVariables struct {
+	Step            int        `json:"step"`
+	FrameId         int        `json:"frameId"`
+	ContractAddress string     `json:"contractAddress"`
+	Function        string     `json:"function"`
+	Parameters      []Variable `json:"parameters"`
+	ReturnValues    []Variable `json:"returnValues"`
+	Locals          []Variable `json:"locals"`
+}
+
+// Condition that stops navigation in the step debugger
+type Breakpoint struct {
+	Type     string `json:"type"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Address  string `json:"address,omitempty"`
+	Selector string `json:"selector,omitempty"`
+	Opcode   string `json:"opcode,omitempty"`
+	Slot     string `json:"slot,omitempty"`
+}
+
+type NavigationRequest struct {
+	Command     string       `json:"command"`
+	From        int          `json:"from"`
+	Breakpoints []Breakpoint `json:"breakpoints"`
+}
+
+type NavigationResult struct {
+	Step       Step        `json:"step"`
+	Reason     string      `json:"reason"`
+	Breakpoint *Breakpoint `json:"breakpoint,omitempty"`
+}
+
+// Unsigned call simulated with debug_traceCall
+type CallSimulation struct {
+	From           string        `json:"from"`
+	To             string        `json:"to"`
+	Data           string        `json:"data"`
+	Value          string        `json:"value"`
+	Gas            string        `json:"gas"`
+	BlockNumber    string        `json:"blockNumber"`
+	TxIndex        *int          `json:"txIndex"` // Run after the first txIndex transactions of blockNumber
+	StateOverrides StateOverride `json:"stateOverrides"`
+}
+
+// Account state override that can also replace the code by a patched version of the verified source
+type AccountOverride struct {
+	evm.AccountOverride
+	PatchedSources map[string]string `json:"patchedSources,omitempty"` // File name to patched content
+}
+type StateOverride map[string]AccountOverride
+
+// Ordered transactions simulated on one fork
+type BundleSimulation struct {
+	Transactions   []BundleTransaction `json:"transactions"`
+	BlockNumber    string              `json:"blockNumber"`
+	TxIndex        *int                `json:"txIndex"`   // Run after the first txIndex transactions of blockNumber
+	SameBlock      bool                `json:"sameBlock"` // Mine all transactions in one block instead of one block each
+	StopOnFailure  bool                `json:"stopOnFailure"`
+	StateOverrides StateOverride       `json:"stateOverrides"`
+}
+
+// Signed raw transaction, or an unsigned one sent from an impersonated account
+type BundleTransaction struct {
+	RawTransaction string `json:"rawTransaction,omitempty"`
+	From           string `json:"from,omitempty"`
+	To             string `json:"to,omitempty"`
+	Data           string `json:"data,omitempty"`
+	Value          string `json:"value,omitempty"`
+	Gas            string `json:"gas,omitempty"`
+}
+
+type BundleResult struct {
+	Success      bool                      `json:"success"`
+	Transactions []BundleTransactionResult `json:"transactions"`
+}
+
+type BundleTransactionResult struct {
+	Index        int                `json:"index"`
+	TxHash       string             `json:"txHash,omitempty"`
+	Status       string             `json:"status"`
+	BlockNumber  uint64             `json:"blockNumber,omitempty"`
+	GasUsed      uint64             `json:"gasUsed"`
+	RevertReason string             `json:"revertReason,omitempty"`
+	Revert       *RevertInfo        `json:"revert,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	Logs         []DecodedLog       `json:"logs"`
+	StateDiff    []AddressStateDiff `json:"stateDiff"`
 }
@@ -2,11 +2,12 @@ package debug
 
 import (
 	"Simulations/src/etherscan"
+	"encoding/hex"
 	"encoding/json"
-	"io"
-	"os"
 	"strconv"
 	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 func decompressSourceMap(sourceMap string) []Opcode {
@@ -70,6 +71,30 @@ func decompressSourceMap(sourceMap string) []Opcode {
 	return decompressedSourceMap
 }
 
+// getInstructionIndexes maps every program counter of the bytecode to its instruction number
+func getInstructionIndexes(bytecode string) map[int]int {
+	code := strings.TrimPrefix(bytecode, "0x")
+	instructionIndexes := make(map[int]int)
+
+	instructionNumber := 0
+	for pc := 0; pc*2+1 < len(code); pc++ {
+		instructionIndexes[pc] = instructionNumber
+		instructionNumber++
+
+		opcode, err := strconv.ParseUint(code[pc*2:pc*2+2], 16, 8)
+		if err != nil {
+			break
+		}
+
+		// PUSH1 to PUSH32 are followed by their immediate bytes
+		if opcode >= 0x60 && opcode <= 0x7f {
+			pc += int(opcode - 0x5f)
+		}
+	}
+
+	return instructionIndexes
+}
+
 func getLineNumber(sourceCode []byte, bytesOffset string) (int, error) {
 	bytesOffsetInt, err := strconv.Atoi(bytesOffset)
 	if err != nil {
@@ -98,46 +123,13 @@ func removeBlankSpaces(currOpcode *Opcode, prevOpcode Opcode) {
 	}
 }
 
-func saveSourceCode(outputDir string, sourceCodeInfo etherscan.SourceCodeInfo, address string) error {
-	// make output directory if it doesn't exist
-	err := os.MkdirAll(outputDir, os.ModePerm)
-	if err != nil && !os.IsExist(err) {
-		return err
-	}
-
-	var fileExtension string
-	if sourceCodeInfo.IsStandardJSON {
-		fileExtension = ".json"
-	} else {
-		fileExtension = ".sol"
-	}
-
-	file, err := os.Create(outputDir + address + fileExtension)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	_, err = file.WriteString(sourceCodeInfo.SourceCode)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func readSourceCodeFromFile(outputDir string, address string) (map[string]string, error) {
-	standardJSONFilePath := outputDir + address + ".json"
+// Source files of the explorer info, keyed by the names used in the compiler output
+func getSourceFiles(info etherscan.SourceCodeInfo, address string) (map[string]string, error) {
 	fileMap := make(map[string]string)
 
-	if fileExists(standardJSONFilePath) {
-		rawData, err := readFile(standardJSONFilePath)
-		if err != nil {
-			return nil, err
-		}
-
+	if info.IsStandardJSON {
 		var standardJsonInput StandardJsonInput
-		err = json.Unmarshal(rawData, &standardJsonInput)
+		err := json.Unmarshal([]byte(info.SourceCode), &standardJsonInput)
 		if err != nil {
 			return nil, err
 		}
@@ -146,55 +138,23 @@ func readSourceCodeFromFile(outputDir string, address string) (map[string]string
 			fileMap[fileName] = inputFile.Content
 		}
 
+	} else if isVyper(info) {
+		fileMap[address+".vy"] = info.SourceCode
 	} else {
-		sourceCode, err := readFile(outputDir + address + ".sol")
-		if err != nil {
-			return nil, err
-		}
-
-		fileMap[address+".sol"] = string(sourceCode)
+		fileMap[address+".sol"] = info.SourceCode
 	}
 
 	return fileMap, nil
 }
 
-func readSourceMappingAndFileIdsFromFile(outputDir string, address string) (CompiledContract, error) {
-	rawData, err := readFile(outputDir + address + ".json")
-	if err != nil {
-		return CompiledContract{}, nil
-	}
-
-	var compiledContract CompiledContract
-	err = json.Unmarshal(rawData, &compiledContract)
-	if err != nil {
-		return CompiledContract{}, err
-	}
-
-	return compiledContract, nil
-}
-
-func fileExists(sourceCodeFile string) bool {
-	fileInfo, err := os.Stat(sourceCodeFile)
-	if !os.IsNotExist(err) && !fileInfo.IsDir() {
-		return true
-	}
-
-	return false
-}
-
-func readFile(filePath string) ([]byte, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	bytes, err := io.ReadAll(file)
+// Cache key of a deployment's code
+func getCodeHash(bytecode string) string {
+	code, err := hex.DecodeString(strings.TrimPrefix(bytecode, "0x"))
 	if err != nil {
-		return nil, err
+		return crypto.Keccak256Hash([]byte(bytecode)).Hex()
 	}
 
-	return bytes, nil
+	return crypto.Keccak256Hash(code).Hex()
 }
 
 func fileIdValid(fileId string, fileIds map[string]string) bool {
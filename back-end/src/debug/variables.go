@@ -0,0 +1,466 @@
+package debug
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Variable declared in the Solidity AST
+type astVariable struct {
+	name     string
+	typeName string
+	location string
+}
+
+// Function or modifier declared in the Solidity AST
+type astFunction struct {
+	name             string
+	fileId           string
+	start            int
+	length           int
+	parameters       []astVariable
+	returnParameters []astVariable
+}
+
+// Declarations of a contract, keyed by the source range ("start:length:file") that pushes them
+type astIndex struct {
+	functions    []astFunction
+	declarations map[string][]astVariable
+}
+
+// Function being executed while walking a frame, with the stack height at its entry
+type variableScope struct {
+	function    *astFunction
+	entryHeight int
+	locals      []scopedVariable
+}
+
+type scopedVariable struct {
+	variable   astVariable
+	stackIndex int
+}
+
+// GetVariables decodes the arguments, return values and local variables in scope at a step
+func (s *Service) GetVariables(sessionId string, stepIndex int) (StepVariables, error) {
+	session, err := s.getStepSession(sessionId)
+	if err != nil {
+		return StepVariables{}, err
+	}
+
+	if stepIndex < 0 || stepIndex >= len(session.structLogs) {
+		return StepVariables{}, fmt.Errorf("step %d out of range", stepIndex)
+	}
+
+	frameId := session.frameIds[stepIndex]
+	contract := session.frameContracts[frameId]
+
+	if isUnverifiedContract(contract.sourceCodes) {
+		return StepVariables{}, errors.New("no verified source for " + contract.address)
+	}
+
+	index, err := session.getAstIndex(contract)
+	if err != nil {
+		return StepVariables{}, err
+	}
+
+	scope := walkScopes(session, index, contract, stepIndex)
+
+	structLog := session.structLogs[stepIndex]
+	stack := formatStack(structLog.Stack)
+	memory := joinMemory(structLog.Memory)
+
+	result := StepVariables{
+		Step:            stepIndex,
+		FrameId:         frameId,
+		ContractAddress: contract.address,
+		Parameters:      []Variable{},
+		ReturnValues:    []Variable{},
+		Locals:          []Variable{},
+	}
+
+	if scope.function != nil {
+		result.Function = scope.function.name
+
+		parameterCount := len(scope.function.parameters)
+		for i, parameter := range scope.function.parameters {
+			stackIndex := scope.entryHeight - parameterCount + i
+			if variable, found := decodeVariable(parameter, stackIndex, stack, memory); found {
+				result.Parameters = append(result.Parameters, variable)
+			}
+		}
+
+		for i, returnParameter := range scope.function.returnParameters {
+			stackIndex := scope.entryHeight + i
+			if variable, found := decodeVariable(returnParameter, stackIndex, stack, memory); found {
+				result.ReturnValues = append(result.ReturnValues, variable)
+			}
+		}
+	} else if entry, found := getSourceMapEntry(contract, structLog.Pc); found {
+		if function := index.functionAt(entry); function != nil {
+			result.Function = function.name
+		}
+	}
+
+	for _, local := range scope.locals {
+		if variable, found := decodeVariable(local.variable, local.stackIndex, stack, memory); found {
+			result.Locals = append(result.Locals, variable)
+		}
+	}
+
+	return result, nil
+}
+
+// walkScopes replays the frame up to stepIndex, entering functions on "i" jumps and leaving them on "o" jumps.
+// A local is assigned the stack slot on top after the last instruction of its declaration.
+func walkScopes(session *stepSession, index *astIndex, contract ContractEntry, stepIndex int) variableScope {
+	frameId := session.frameIds[stepIndex]
+	scopes := []variableScope{{entryHeight: -1}}
+	enteringFunction := false
+
+	for i := 0; i <= stepIndex; i++ {
+		if session.frameIds[i] != frameId {
+			continue
+		}
+
+		structLog := session.structLogs[i]
+		entry, found := getSourceMapEntry(contract, structLog.Pc)
+
+		if enteringFunction {
+			enteringFunction = false
+			if structLog.Op == "JUMPDEST" && found {
+				if function := index.functionAt(entry); function != nil {
+					scopes = append(scopes, variableScope{function: function, entryHeight: len(structLog.Stack)})
+				}
+			}
+		}
+
+		if !found {
+			continue
+		}
+
+		key := entry.Offset + ":" + entry.Length + ":" + entry.FileID
+		if declarations, exists := index.declarations[key]; exists && i < stepIndex && session.frameIds[i+1] == frameId {
+			height := len(session.structLogs[i+1].Stack)
+			scope := &scopes[len(scopes)-1]
+			for j, declaration := range declarations {
+				if declaration.name != "" {
+					scope.declare(declaration, height-len(declarations)+j)
+				}
+			}
+		}
+
+		if structLog.Op == "JUMP" {
+			switch entry.JumpType {
+			case "i":
+				enteringFunction = true
+			case "o":
+				if len(scopes) > 1 {
+					scopes = scopes[:len(scopes)-1]
+				}
+			}
+		}
+	}
+
+	return scopes[len(scopes)-1]
+}
+
+// Records a local, replacing any variable previously held in the same slot
+func (scope *variableScope) declare(variable astVariable, stackIndex int) {
+	var locals []scopedVariable
+	for _, local := range scope.locals {
+		if local.stackIndex != stackIndex && local.variable != variable {
+			locals = append(locals, local)
+		}
+	}
+
+	scope.locals = append(locals, scopedVariable{variable: variable, stackIndex: stackIndex})
+}
+
+// Innermost function or modifier whose body contains the source range
+func (index *astIndex) functionAt(entry Opcode) *astFunction {
+	offset, err := strconv.Atoi(entry.Offset)
+	if err != nil {
+		return nil
+	}
+
+	var innermost *astFunction
+	for i, function := range index.functions {
+		if function.fileId != entry.FileID || offset < function.start || offset >= function.start+function.length {
+			continue
+		}
+
+		if innermost == nil || function.length < innermost.length {
+			innermost = &index.functions[i]
+		}
+	}
+
+	return innermost
+}
+
+func (session *stepSession) getAstIndex(contract ContractEntry) (*astIndex, error) {
+	session.astMutex.Lock()
+	defer session.astMutex.Unlock()
+
+	if index, exists := session.astIndexes[contract.address]; exists {
+		return index, nil
+	}
+
+	if len(contract.asts) == 0 {
+		return nil, errors.New("no AST available for " + contract.address + ", recompile the contract")
+	}
+
+	index, err := buildAstIndex(contract.asts)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.astIndexes == nil {
+		session.astIndexes = make(map[string]*astIndex)
+	}
+	session.astIndexes[contract.address] = index
+
+	return index, nil
+}
+
+func buildAstIndex(asts map[string]json.RawMessage) (*astIndex, error) {
+	index := &astIndex{declarations: make(map[string][]astVariable)}
+
+	for fileName, rawAst := range asts {
+		var ast interface{}
+		err := json.Unmarshal(rawAst, &ast)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AST for %s: %v", fileName, err)
+		}
+
+		walkAst(ast, func(node map[string]interface{}) {
+			switch node["nodeType"] {
+			case "FunctionDefinition", "ModifierDefinition":
+				start, length, fileId, valid := parseSrc(node["src"])
+				if !valid {
+					return
+				}
+
+				name, _ := node["name"].(string)
+				if name == "" {
+					name, _ = node["kind"].(string)
+				}
+
+				index.functions = append(index.functions, astFunction{
+					name:             name,
+					fileId:           fileId,
+					start:            start,
+					length:           length,
+					parameters:       getParameterList(node["parameters"]),
+					returnParameters: getParameterList(node["returnParameters"]),
+				})
+
+			case "VariableDeclaration":
+				if stateVariable, _ := node["stateVariable"].(bool); stateVariable {
+					return
+				}
+
+				if src, valid := node["src"].(string); valid {
+					index.declarations[src] = []astVariable{newAstVariable(node)}
+				}
+
+			case "VariableDeclarationStatement":
+				initialValue, _ := node["initialValue"].(map[string]interface{})
+				declarations, _ := node["declarations"].([]interface{})
+				src, valid := initialValue["src"].(string)
+				if !valid {
+					return
+				}
+
+				// Tuple assignments can leave gaps, which still take a stack slot
+				variables := make([]astVariable, len(declarations))
+				for i, declaration := range declarations {
+					if declarationNode, isNode := declaration.(map[string]interface{}); isNode {
+						variables[i] = newAstVariable(declarationNode)
+					}
+				}
+				index.declarations[src] = variables
+			}
+		})
+	}
+
+	return index, nil
+}
+
+func walkAst(node interface{}, visit func(map[string]interface{})) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		visit(value)
+		for _, child := range value {
+			walkAst(child, visit)
+		}
+	case []interface{}:
+		for _, child := range value {
+			walkAst(child, visit)
+		}
+	}
+}
+
+func getParameterList(node interface{}) []astVariable {
+	parameterList, _ := node.(map[string]interface{})
+	parameters, _ := parameterList["parameters"].([]interface{})
+
+	var variables []astVariable
+	for _, parameter := range parameters {
+		if parameterNode, isNode := parameter.(map[string]interface{}); isNode {
+			variables = append(variables, newAstVariable(parameterNode))
+		}
+	}
+
+	return variables
+}
+
+func newAstVariable(node map[string]interface{}) astVariable {
+	variable := astVariable{}
+	variable.name, _ = node["name"].(string)
+	variable.location, _ = node["storageLocation"].(string)
+
+	if typeDescriptions, isMap := node["typeDescriptions"].(map[string]interface{}); isMap {
+		variable.typeName, _ = typeDescriptions["typeString"].(string)
+	}
+
+	return variable
+}
+
+// Parses an AST "start:length:file" source range
+func parseSrc(src interface{}) (int, int, string, bool) {
+	srcString, _ := src.(string)
+	parts := strings.Split(srcString, ":")
+	if len(parts) != 3 {
+		return 0, 0, "", false
+	}
+
+	start, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, "", false
+	}
+
+	length, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, "", false
+	}
+
+	return start, length, parts[2], true
+}
+
+func getSourceMapEntry(contract ContractEntry, pc int) (Opcode, bool) {
+	opcodeNumber, exists := contract.instructionIndexes[pc]
+	if !exists || opcodeNumber >= len(contract.decompressedSourceMap) {
+		return Opcode{}, false
+	}
+
+	return contract.decompressedSourceMap[opcodeNumber], true
+}
+
+func joinMemory(memory []string) []byte {
+	var joined strings.Builder
+	for _, word := range memory {
+		joined.WriteString(strings.TrimPrefix(word, "0x"))
+	}
+
+	decoded, _ := hex.DecodeString(joined.String())
+	return decoded
+}
+
+func decodeVariable(variable astVariable, stackIndex int, stack []string, memory []byte) (Variable, bool) {
+	if stackIndex < 0 || stackIndex >= len(stack) {
+		return Variable{}, false
+	}
+
+	word := parseBigHex(stack[stackIndex])
+
+	decoded := Variable{
+		Name:       variable.name,
+		Type:       variable.typeName,
+		Location:   variable.location,
+		StackIndex: stackIndex,
+		Value:      decodeStackValue(variable, word, memory),
+	}
+
+	return decoded, true
+}
+
+// Decodes a value type from its stack word, or a dynamic string/bytes from the memory it points to
+func decodeStackValue(variable astVariable, word *big.Int, memory []byte) interface{} {
+	typeName := strings.Fields(variable.typeName)
+	if len(typeName) == 0 {
+		return fmt.Sprintf("0x%x", word)
+	}
+	baseType := typeName[0]
+
+	switch variable.location {
+	case "memory":
+		if baseType == "string" || baseType == "bytes" {
+			if data, found := readMemoryBytes(memory, word); found {
+				if baseType == "string" {
+					return string(data)
+				}
+				return "0x" + hex.EncodeToString(data)
+			}
+		}
+		return fmt.Sprintf("memory pointer 0x%x", word)
+	case "storage", "calldata":
+		return fmt.Sprintf("%s pointer 0x%x", variable.location, word)
+	}
+
+	switch {
+	case baseType == "bool":
+		return word.Sign() != 0
+	case baseType == "address" || baseType == "contract" || baseType == "interface":
+		return common.BigToAddress(word).Hex()
+	case baseType == "enum" || strings.HasPrefix(baseType, "uint"):
+		return word.String()
+	case strings.HasPrefix(baseType, "int"):
+		return toSigned(word, baseType).String()
+	case strings.HasPrefix(baseType, "bytes"):
+		size, err := strconv.Atoi(strings.TrimPrefix(baseType, "bytes"))
+		if err != nil || size < 1 || size > 32 {
+			break
+		}
+		return "0x" + hex.EncodeToString(common.BigToHash(word).Bytes()[:size])
+	}
+
+	return fmt.Sprintf("0x%x", word)
+}
+
+// Interprets the low bits of a word as a two's complement intN
+func toSigned(word *big.Int, typeName string) *big.Int {
+	bits, err := strconv.Atoi(strings.TrimPrefix(typeName, "int"))
+	if err != nil || bits < 8 || bits > 256 {
+		bits = 256
+	}
+
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	value := new(big.Int).Mod(word, modulus)
+	if value.Bit(bits-1) == 1 {
+		value.Sub(value, modulus)
+	}
+
+	return value
+}
+
+// Reads a length-prefixed byte array from memory
+func readMemoryBytes(memory []byte, pointer *big.Int) ([]byte, bool) {
+	if !pointer.IsInt64() || pointer.Int64()+32 > int64(len(memory)) {
+		return nil, false
+	}
+	start := pointer.Int64()
+
+	length := new(big.Int).SetBytes(memory[start : start+32])
+	if !length.IsInt64() || start+32+length.Int64() > int64(len(memory)) {
+		return nil, false
+	}
+
+	return memory[start+32 : start+32+length.Int64()], true
+}
@@ -0,0 +1,80 @@
+package debug
+
+import (
+	"Simulations/src/etherscan"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// VerifyBytecode recompiles the explorer source of a contract and compares it with the deployed code, on the
+// fork when forkId is set and upstream otherwise. Line mappings can be trusted unless the report is a mismatch.
+func (s *Service) VerifyBytecode(forkId string, address string) (VerificationReport, error) {
+	if !common.IsHexAddress(address) {
+		return VerificationReport{}, errors.New("invalid address")
+	}
+
+	var deployedCode string
+	var err error
+	if forkId != "" {
+		deployedCode, err = s.evmService.GetContractBytecode(forkId, address)
+	} else {
+		deployedCode, err = s.evmService.GetUpstreamCode(address)
+	}
+	if err != nil {
+		return VerificationReport{}, err
+	}
+
+	if strings.TrimPrefix(deployedCode, "0x") == "" {
+		return VerificationReport{}, fmt.Errorf("no code deployed at %s", address)
+	}
+
+	codeHash := getCodeHash(deployedCode)
+	info, verified, err := s.getSourceCodeInfo(address, codeHash)
+	if err != nil {
+		return VerificationReport{}, err
+	}
+
+	if !verified {
+		return VerificationReport{}, etherscan.ErrNotVerified
+	}
+
+	report := VerificationReport{
+		Address:         address,
+		CodeHash:        codeHash,
+		CompilerVersion: info.CompilerVersion,
+	}
+
+	if isVyper(info) {
+		vyper, err := s.compilerService.GetVyper(info.CompilerVersion)
+		if err != nil {
+			return VerificationReport{}, err
+		}
+
+		output, err := runVyper(vyper, info, address)
+		if err != nil {
+			return VerificationReport{}, err
+		}
+
+		report.Contract = output.contract
+		report.CodeComparison = compareVyperCode(output.bytecode, deployedCode)
+	} else {
+		solc, err := s.compilerService.GetSolc(info.CompilerVersion)
+		if err != nil {
+			return VerificationReport{}, err
+		}
+
+		_, contract, err := compileSolidity(solc, info, address, deployedCode)
+		if err != nil {
+			return VerificationReport{}, err
+		}
+
+		report.Contract = contract.fileName + ":" + contract.name
+		report.CodeComparison = contract.comparison
+	}
+
+	fmt.Printf("🔎 %s at %s: %s\n", report.Contract, address, report.Status)
+	return report, nil
+}
@@ -0,0 +1,250 @@
+package debug
+
+import (
+	"Simulations/src/etherscan"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Explorers report Vyper compilers as vyper:<version>
+func isVyper(info etherscan.SourceCodeInfo) bool {
+	return strings.HasPrefix(strings.ToLower(info.CompilerVersion), "vyper")
+}
+
+// Compiled runtime code of a Vyper contract and its source map
+type vyperOutput struct {
+	mainFile   string
+	contract   string // Fully qualified name of the compiled contract
+	sourceCode []byte
+	bytecode   string
+	sourceMap  VyperSourceMap
+}
+
+// Compiles a Vyper contract and returns the CompiledContract JSON, with the Vyper source map converted
+// to the Solidity format so the rest of the debugger doesn't need to know the difference
+func compileVyperContract(vyper string, info etherscan.SourceCodeInfo, address string, deployedCode string) ([]byte, error) {
+	output, err := runVyper(vyper, info, address)
+	if err != nil {
+		return nil, err
+	}
+
+	compiledContract := CompiledContract{
+		Srcmap:   convertVyperSourceMap(output.bytecode, output.sourceMap, output.sourceCode),
+		Sources:  map[string]string{"0": output.mainFile},
+		Contract: output.contract,
+	}
+
+	if compareVyperCode(output.bytecode, deployedCode).Status == VerificationMismatch {
+		fmt.Printf("⚠️  Recompiled %s doesn't match the code deployed at %s, ignoring its source map\n", compiledContract.Contract, address)
+		compiledContract.Srcmap = ""
+		compiledContract.BytecodeMismatch = true
+	}
+
+	return json.MarshalIndent(compiledContract, "", "    ")
+}
+
+func runVyper(vyper string, info etherscan.SourceCodeInfo, address string) (vyperOutput, error) {
+	workDir, err := os.MkdirTemp("", "compile-"+address+"-")
+	if err != nil {
+		return vyperOutput{}, err
+	}
+	defer os.RemoveAll(workDir)
+
+	mainFile, evmVersion, err := writeVyperSources(workDir, info, address)
+	if err != nil {
+		return vyperOutput{}, err
+	}
+
+	cmd := exec.Command(vyper, "-f", "bytecode_runtime,source_map", "-p", workDir, mainFile)
+	cmd.Dir = workDir
+
+	if evmVersion != "" && strings.ToLower(evmVersion) != "default" {
+		cmd.Args = append(cmd.Args, "--evm-version", strings.ToLower(evmVersion))
+	}
+
+	rawOutput, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return vyperOutput{}, fmt.Errorf("vyper failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return vyperOutput{}, err
+	}
+
+	// One line per output format, in the order they were asked for
+	lines := strings.SplitN(strings.TrimSpace(string(rawOutput)), "\n", 2)
+	if len(lines) != 2 {
+		return vyperOutput{}, errors.New("unexpected vyper output")
+	}
+
+	_, name := splitContractName(info)
+	output := vyperOutput{mainFile: mainFile, contract: mainFile + ":" + name, bytecode: strings.TrimSpace(lines[0])}
+	err = json.Unmarshal([]byte(lines[1]), &output.sourceMap)
+	if err != nil {
+		return vyperOutput{}, err
+	}
+
+	output.sourceCode, err = os.ReadFile(filepath.Join(workDir, mainFile))
+	if err != nil {
+		return vyperOutput{}, err
+	}
+
+	return output, nil
+}
+
+// Vyper appends immutables to the runtime code at deployment and has no metadata in it,
+// so the compiled code has to be an exact prefix of the deployed one
+func compareVyperCode(bytecode string, deployedCode string) CodeComparison {
+	compiled, err := hex.DecodeString(strings.TrimPrefix(bytecode, "0x"))
+	deployed, _ := hex.DecodeString(strings.TrimPrefix(deployedCode, "0x"))
+	comparison := CodeComparison{CompiledLength: len(compiled), DeployedLength: len(deployed)}
+
+	if err != nil {
+		comparison.Status = VerificationMismatch
+		comparison.Differences = []ByteRange{{Start: 0, End: max(len(compiled), len(deployed))}}
+		return comparison
+	}
+
+	if len(compiled) <= len(deployed) && bytes.Equal(compiled, deployed[:len(compiled)]) {
+		comparison.Status = VerificationExactMatch
+		if len(deployed) > len(compiled) {
+			comparison.IgnoredRanges = []ByteRange{{Start: len(compiled), End: len(deployed), Reason: "immutable"}}
+		}
+		return comparison
+	}
+
+	comparison.Status = VerificationMismatch
+	comparison.Differences = diffRanges(compiled, deployed[:min(len(compiled), len(deployed))])
+	return comparison
+}
+
+// Writes the explorer sources to workDir and returns the file to compile, named as in getSourceFiles
+func writeVyperSources(workDir string, info etherscan.SourceCodeInfo, address string) (string, string, error) {
+	if !info.IsStandardJSON {
+		mainFile := address + ".vy"
+		err := os.WriteFile(filepath.Join(workDir, mainFile), []byte(info.SourceCode), 0644)
+		return mainFile, info.EVMVersion, err
+	}
+
+	var input VyperJsonInput
+	err := json.Unmarshal([]byte(info.SourceCode), &input)
+	if err != nil {
+		return "", "", err
+	}
+
+	files := make(map[string][]byte)
+	for fileName, inputFile := range input.Sources {
+		files[fileName] = []byte(inputFile.Content)
+	}
+	for fileName, inputInterface := range input.Interfaces {
+		if inputInterface.Content != "" {
+			files[fileName] = []byte(inputInterface.Content)
+		} else if len(inputInterface.Abi) > 0 {
+			files[fileName] = inputInterface.Abi
+		}
+	}
+
+	for fileName, content := range files {
+		filePath := filepath.Join(workDir, filepath.FromSlash(fileName))
+		if !strings.HasPrefix(filePath, workDir+string(filepath.Separator)) {
+			return "", "", fmt.Errorf("invalid source path %s", fileName)
+		}
+
+		err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+		if err != nil {
+			return "", "", err
+		}
+
+		err = os.WriteFile(filePath, content, 0644)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	// The deployed contract is the source named after it, or the only one there is
+	fileHint, name := splitContractName(info)
+	mainFile := ""
+	for fileName := range input.Sources {
+		if fileName == fileHint || path.Base(fileName) == name+".vy" || len(input.Sources) == 1 {
+			mainFile = fileName
+			break
+		}
+	}
+
+	if mainFile == "" {
+		return "", "", fmt.Errorf("no source file for contract %s", name)
+	}
+
+	evmVersion := input.Settings.EvmVersion
+	if evmVersion == "" {
+		evmVersion = info.EVMVersion
+	}
+
+	return mainFile, evmVersion, nil
+}
+
+// Builds an uncompressed Solidity source map with one entry per instruction of the runtime bytecode.
+// Instructions Vyper doesn't map to the source get -1 offsets, which getSourceLocation skips.
+func convertVyperSourceMap(bytecode string, sourceMap VyperSourceMap, sourceCode []byte) string {
+	instructionIndexes := getInstructionIndexes(bytecode)
+	entries := make([]string, len(instructionIndexes))
+	for i := range entries {
+		entries[i] = "-1:-1:-1:-"
+	}
+
+	lineOffsets := []int{0}
+	for i, char := range sourceCode {
+		if char == '\n' {
+			lineOffsets = append(lineOffsets, i+1)
+		}
+	}
+
+	// Lines are 1-based and columns are byte offsets within the line
+	toOffset := func(line *int, col *int) (int, bool) {
+		if line == nil || col == nil || *line < 1 || *line > len(lineOffsets) {
+			return 0, false
+		}
+		offset := lineOffsets[*line-1] + *col
+		return offset, offset <= len(sourceCode)
+	}
+
+	for rawPc, position := range sourceMap.PcPosMap {
+		pc, err := strconv.Atoi(rawPc)
+		if err != nil || len(position) < 4 {
+			continue
+		}
+
+		instructionIndex, exists := instructionIndexes[pc]
+		if !exists {
+			continue
+		}
+
+		start, valid := toOffset(position[0], position[1])
+		if !valid {
+			continue
+		}
+
+		length := 0
+		if end, valid := toOffset(position[2], position[3]); valid && end > start {
+			length = end - start
+		}
+
+		jumpType := sourceMap.PcJumpMap[rawPc]
+		if jumpType == "" {
+			jumpType = "-"
+		}
+
+		entries[instructionIndex] = fmt.Sprintf("%d:%d:0:%s", start, length, jumpType)
+	}
+
+	return strings.Join(entries, ";")
+}
@@ -0,0 +1,67 @@
+package debug
+
+import "testing"
+
+func TestConvertVyperSourceMap(t *testing.T) {
+	position := func(values ...int) []*int {
+		pointers := make([]*int, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		return pointers
+	}
+
+	// PUSH1 1, PUSH1 2, ADD
+	bytecode := "0x6001600201"
+	sourceCode := []byte("a = 1\nb = 2\n")
+
+	tests := []struct {
+		name      string
+		sourceMap VyperSourceMap
+		expected  string
+	}{
+		{
+			name:      "unmapped instructions",
+			sourceMap: VyperSourceMap{},
+			expected:  "-1:-1:-1:-;-1:-1:-1:-;-1:-1:-1:-",
+		},
+		{
+			name: "lines and columns become offsets",
+			sourceMap: VyperSourceMap{
+				PcPosMap:  map[string][]*int{"0": position(1, 0, 1, 5), "4": position(2, 4, 2, 5)},
+				PcJumpMap: map[string]string{"4": "i"},
+			},
+			expected: "0:5:0:-;-1:-1:-1:-;10:1:0:i",
+		},
+		{
+			name: "program counters inside push data are skipped",
+			sourceMap: VyperSourceMap{
+				PcPosMap: map[string][]*int{"1": position(1, 0, 1, 5), "3": position(2, 0, 2, 5)},
+			},
+			expected: "-1:-1:-1:-;-1:-1:-1:-;-1:-1:-1:-",
+		},
+		{
+			name: "missing and out of range positions are skipped",
+			sourceMap: VyperSourceMap{
+				PcPosMap: map[string][]*int{"0": {nil, nil, nil, nil}, "2": position(9, 0, 9, 1), "4": position(1, 0)},
+			},
+			expected: "-1:-1:-1:-;-1:-1:-1:-;-1:-1:-1:-",
+		},
+		{
+			name: "end before start has no length",
+			sourceMap: VyperSourceMap{
+				PcPosMap: map[string][]*int{"2": position(2, 0, 1, 0)},
+			},
+			expected: "-1:-1:-1:-;6:0:0:-;-1:-1:-1:-",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			converted := convertVyperSourceMap(bytecode, test.sourceMap, sourceCode)
+			if converted != test.expected {
+				t.Errorf("convertVyperSourceMap() = %q, expected %q", converted, test.expected)
+			}
+		})
+	}
+}
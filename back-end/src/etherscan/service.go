@@ -4,13 +4,25 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
+// Returned when the explorer has no verified source for a contract, as opposed to a failed request
+var ErrNotVerified = errors.New("contract source code not verified")
+
+// Etherscan v2 API, serving every supported chain
+const etherscanApiUrl = "https://api.etherscan.io/v2/api"
+
+// Service talks to an explorer with an Etherscan compatible API
 type Service struct {
-	etherscanApiKey string
+	apiUrl  string
+	apiKey  string
+	chainId string
 }
 
 type EtherScanService interface {
@@ -18,10 +30,33 @@ type EtherScanService interface {
 	GetAbi(address string) (string, error)
 }
 
-func NewService(etherscanApiKey string) *Service {
+func NewService(etherscanApiKey string, chainId string) *Service {
+	return NewExplorerService(etherscanApiUrl, etherscanApiKey, chainId)
+}
+
+// NewExplorerService uses another explorer with the same API, such as Blockscout's /api. chainId and
+// apiKey are left out of requests when empty, single chain explorers don't take a chain id.
+func NewExplorerService(apiUrl string, apiKey string, chainId string) *Service {
 	return &Service{
-		etherscanApiKey: etherscanApiKey,
+		apiUrl:  apiUrl,
+		apiKey:  apiKey,
+		chainId: chainId,
+	}
+}
+
+func (s *Service) requestUrl(action string, address string) string {
+	query := url.Values{}
+	if s.chainId != "" {
+		query.Set("chainid", s.chainId)
+	}
+	query.Set("module", "contract")
+	query.Set("action", action)
+	query.Set("address", address)
+	if s.apiKey != "" {
+		query.Set("apikey", s.apiKey)
 	}
+
+	return s.apiUrl + "?" + query.Encode()
 }
 
 func (s *Service) GetSourceCodeInfo(address string) (SourceCodeInfo, error) {
@@ -44,14 +79,7 @@ func (s *Service) GetSourceCodeInfo(address string) (SourceCodeInfo, error) {
 }
 
 func (s *Service) getSourceCodeInfo(address string) (SourceCodeInfo, error) {
-	url := "https://api.etherscan.io/v2/api" +
-		"?chainid=999" +
-		"&module=contract" +
-		"&action=getsourcecode" +
-		"&address=" + address +
-		"&apikey=" + s.etherscanApiKey
-
-	resp, err := http.Get(url)
+	resp, err := http.Get(s.requestUrl("getsourcecode", address))
 	if err != nil {
 		return SourceCodeInfo{}, err
 	}
@@ -73,31 +101,94 @@ func (s *Service) getSourceCodeInfo(address string) (SourceCodeInfo, error) {
 		return SourceCodeInfo{}, errors.New(errorRes.Result)
 	}
 
-	if sourceCodeRes.Status != "1" || sourceCodeRes.Message != "OK" || len(sourceCodeRes.Result[0].SourceCode) == 0 {
+	if sourceCodeRes.Status != "1" || sourceCodeRes.Message != "OK" || len(sourceCodeRes.Result) == 0 {
 		return SourceCodeInfo{}, errors.New("failed to get source code")
 	}
 
-	// Remove the first and last curly braces if the source code is standard JSON
-	sourceCode := sourceCodeRes.Result[0].SourceCode
-	isStandardJSON := false
-	if sourceCode[0] == '{' {
-		sourceCode = sourceCode[1 : len(sourceCode)-1]
-		isStandardJSON = true
+	if len(sourceCodeRes.Result[0].SourceCode) == 0 {
+		return SourceCodeInfo{}, ErrNotVerified
 	}
 
 	info := SourceCodeInfo{
-		SourceCode:       sourceCode,
+		SourceCode:       sourceCodeRes.Result[0].SourceCode,
 		ContractName:     sourceCodeRes.Result[0].ContractName,
 		OptimizationUsed: sourceCodeRes.Result[0].OptimizationUsed,
 		CompilerVersion:  sourceCodeRes.Result[0].CompilerVersion,
 		Runs:             sourceCodeRes.Result[0].Runs,
 		EVMVersion:       sourceCodeRes.Result[0].EVMVersion,
-		IsStandardJSON:   isStandardJSON,
+		Library:          sourceCodeRes.Result[0].Library,
+		ContractFileName: sourceCodeRes.Result[0].ContractFileName,
+	}
+	if info.ContractFileName == "" {
+		info.ContractFileName = sourceCodeRes.Result[0].FileName
+	}
+
+	// Standard JSON inputs are wrapped in an extra pair of curly braces, multi-file sources are a bare
+	// map of files that is turned into a standard JSON input with the explorer's settings
+	sourceCode := strings.TrimSpace(info.SourceCode)
+	if strings.HasPrefix(sourceCode, "{{") {
+		info.SourceCode = sourceCode[1 : len(sourceCode)-1]
+		info.IsStandardJSON = true
+	} else if strings.HasPrefix(sourceCode, "{") {
+		input, err := multiFileInput(info, sourceCode)
+		if err != nil {
+			return SourceCodeInfo{}, err
+		}
+
+		info.SourceCode = input
+		info.IsStandardJSON = true
+	} else if len(sourceCodeRes.Result[0].AdditionalSources) > 0 && info.ContractFileName != "" {
+		// Blockscout returns the main file of multi-file contracts and lists the others separately
+		files := map[string]map[string]string{info.ContractFileName: {"content": info.SourceCode}}
+		for _, additionalSource := range sourceCodeRes.Result[0].AdditionalSources {
+			files[additionalSource.Filename] = map[string]string{"content": additionalSource.SourceCode}
+		}
+
+		rawFiles, err := json.Marshal(files)
+		if err != nil {
+			return SourceCodeInfo{}, err
+		}
+
+		input, err := multiFileInput(info, string(rawFiles))
+		if err != nil {
+			return SourceCodeInfo{}, err
+		}
+
+		info.SourceCode = input
+		info.IsStandardJSON = true
 	}
 
 	return info, nil
 }
 
+func multiFileInput(info SourceCodeInfo, sources string) (string, error) {
+	language := "Solidity"
+	settings := make(map[string]interface{})
+	if strings.HasPrefix(strings.ToLower(info.CompilerVersion), "vyper") {
+		language = "Vyper"
+	} else {
+		runs, _ := strconv.Atoi(info.Runs)
+		settings["optimizer"] = map[string]interface{}{"enabled": info.OptimizationUsed == "1", "runs": runs}
+	}
+
+	if info.EVMVersion != "" && strings.ToLower(info.EVMVersion) != "default" {
+		settings["evmVersion"] = strings.ToLower(info.EVMVersion)
+	}
+
+	input := map[string]interface{}{
+		"language": language,
+		"sources":  json.RawMessage(sources),
+		"settings": settings,
+	}
+
+	rawData, err := json.Marshal(input)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid multi-file source code")
+	}
+
+	return string(rawData), nil
+}
+
 func (s *Service) GetAbi(address string) (string, error) {
 	var abi string
 	var err error
@@ -118,14 +209,7 @@ func (s *Service) GetAbi(address string) (string, error) {
 }
 
 func (s *Service) getAbi(address string) (string, error) {
-	url := "https://api.etherscan.io/v2/api" +
-		"?chainid=999" +
-		"&module=contract" +
-		"&action=getabi" +
-		"&address=" + address +
-		"&apikey=" + s.etherscanApiKey
-
-	resp, err := http.Get(url)
+	resp, err := http.Get(s.requestUrl("getabi", address))
 	if err != nil {
 		return "", err
 	}
@@ -141,6 +225,11 @@ func (s *Service) getAbi(address string) (string, error) {
 		return "", err
 	}
 
+	// Blockscout reports it in the message with a null result
+	if abiRes.Result == "Contract source code not verified" || abiRes.Message == "Contract source code not verified" {
+		return "", ErrNotVerified
+	}
+
 	if abiRes.Status != "1" || abiRes.Message != "OK" {
 		return "", errors.New(abiRes.Result)
 	}
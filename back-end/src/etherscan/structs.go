@@ -8,6 +8,8 @@ type SourceCodeInfo struct {
 	OptimizationUsed string
 	Runs             string
 	EVMVersion       string
+	Library          string // Linked libraries as Name:address pairs separated by semicolons
+	ContractFileName string // Source file declaring the contract, not returned by every explorer
 	IsStandardJSON   bool
 }
 
@@ -21,6 +23,14 @@ type SourceCodeResponse struct {
 		OptimizationUsed string `json:"OptimizationUsed"`
 		Runs             string `json:"Runs"`
 		EVMVersion       string `json:"EVMVersion"`
+		Library          string `json:"Library"`
+		ContractFileName string `json:"ContractFileName"`
+		// Blockscout only
+		FileName          string `json:"FileName"`
+		AdditionalSources []struct {
+			Filename   string `json:"Filename"`
+			SourceCode string `json:"SourceCode"`
+		} `json:"AdditionalSources"`
 	}
 }
 
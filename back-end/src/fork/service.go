@@ -158,3 +158,14 @@ func (s *Service) ForwardRpcRequest(forkId string, rawData []byte) (*http.Respon
 
 	return res, nil
 }
+
+// ForwardUpstreamRpcRequest sends a request to the chain the forks are created from
+func (s *Service) ForwardUpstreamRpcRequest(rawData []byte) (*http.Response, error) {
+	res, err := http.Post(s.rpcUrl, "application/json", bytes.NewBuffer(rawData))
+	if err != nil {
+		log.Error("There was a problem with forwarding the RPC request upstream!")
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,365 @@
+package jobs
+
+import (
+	"Simulations/src/debug"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Event types
+const (
+	EventStatus        = "status"
+	EventStage         = "stage"
+	EventLog           = "log"
+	EventPartialResult = "partialResult"
+	EventResult        = "result"
+)
+
+// Finished jobs are kept this long for clients to poll their result
+const jobRetention = time.Hour
+
+// Log events kept per job, later messages are only printed so a retained job stays small
+const maxLogEvents = 500
+
+type debugService interface {
+	SimulateRawTransactionWithProgress(progress debug.Progress, rawData []byte, blockNumber string, txIndex *int, stateOverrides debug.StateOverride) (debug.SimulationResult, error)
+}
+
+type forkService interface {
+	DeleteFork(forkId string) error
+}
+
+type Service struct {
+	debugService debugService
+	forkService  forkService
+	jobs         map[string]*job
+	mutex        sync.Mutex
+	queue        chan *job
+}
+
+// A job and the forks created for it, it is the progress of its simulation. The forks are deleted
+// by the simulation when it ends, or by the cancellation, whichever takes them first.
+type job struct {
+	service   *Service
+	state     Job
+	request   SimulationRequest
+	forks     []string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	cancelled bool
+	events    []Event
+	logEvents int
+	changed   chan struct{} // Closed and replaced whenever an event is added
+	mutex     sync.Mutex
+}
+
+func NewService(debugService debugService, forkService forkService, workers int) *Service {
+	s := &Service{
+		debugService: debugService,
+		forkService:  forkService,
+		jobs:         make(map[string]*job),
+		queue:        make(chan *job, 1000),
+	}
+
+	for i := 0; i < workers; i++ {
+		go s.worker()
+	}
+
+	return s
+}
+
+// SubmitSimulation queues a raw transaction simulation and returns its job right away
+func (s *Service) SubmitSimulation(request SimulationRequest) (Job, error) {
+	now := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	newJob := &job{
+		service: s,
+		state: Job{
+			Id:        uuid.New().String(),
+			Status:    StatusQueued,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		request: request,
+		ctx:     ctx,
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+	newJob.emit(Event{Type: EventStatus, Status: StatusQueued})
+
+	s.mutex.Lock()
+	s.removeExpiredJobs()
+	s.jobs[newJob.state.Id] = newJob
+	s.mutex.Unlock()
+
+	select {
+	case s.queue <- newJob:
+	default:
+		s.mutex.Lock()
+		delete(s.jobs, newJob.state.Id)
+		s.mutex.Unlock()
+		cancel()
+		return Job{}, errors.New("too many queued jobs")
+	}
+
+	return newJob.snapshot(), nil
+}
+
+func (s *Service) GetJob(jobId string) (Job, error) {
+	existingJob, err := s.getJob(jobId)
+	if err != nil {
+		return Job{}, err
+	}
+
+	return existingJob.snapshot(), nil
+}
+
+// CancelJob stops a queued or running job and tears down the forks it created
+func (s *Service) CancelJob(jobId string) (Job, error) {
+	existingJob, err := s.getJob(jobId)
+	if err != nil {
+		return Job{}, err
+	}
+
+	existingJob.mutex.Lock()
+	if existingJob.isFinished() {
+		existingJob.mutex.Unlock()
+		return existingJob.snapshot(), nil
+	}
+
+	existingJob.cancelled = true
+	existingJob.setStatus(StatusCancelled)
+	forks := existingJob.forks
+	existingJob.forks = nil
+	existingJob.mutex.Unlock()
+
+	// The simulation stops at its next RPC call and leaves the forks taken here alone
+	existingJob.cancel()
+
+	for _, forkId := range forks {
+		s.deleteFork(forkId)
+	}
+
+	return existingJob.snapshot(), nil
+}
+
+// NextEvents returns the events of a job after the given event id. When there are none yet it waits for
+// new ones until stop is closed or the timeout expires. Finished is set once the job has no more events.
+func (s *Service) NextEvents(jobId string, after int, stop <-chan struct{}, timeout time.Duration) ([]Event, bool, error) {
+	existingJob, err := s.getJob(jobId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if after < 0 {
+		after = 0
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		existingJob.mutex.Lock()
+		if after < len(existingJob.events) {
+			events := make([]Event, len(existingJob.events)-after)
+			copy(events, existingJob.events[after:])
+			existingJob.mutex.Unlock()
+			return events, false, nil
+		}
+
+		if existingJob.isFinished() {
+			existingJob.mutex.Unlock()
+			return nil, true, nil
+		}
+
+		changed := existingJob.changed
+		existingJob.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-stop:
+			return nil, false, nil
+		case <-timer.C:
+			return nil, false, nil
+		}
+	}
+}
+
+func (s *Service) getJob(jobId string) (*job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existingJob, exists := s.jobs[jobId]
+	if !exists {
+		return nil, errors.New("job not found")
+	}
+
+	return existingJob, nil
+}
+
+// Must be called with the service mutex held
+func (s *Service) removeExpiredJobs() {
+	for jobId, existingJob := range s.jobs {
+		existingJob.mutex.Lock()
+		expired := existingJob.isFinished() && time.Since(existingJob.state.UpdatedAt) > jobRetention
+		existingJob.mutex.Unlock()
+
+		if expired {
+			delete(s.jobs, jobId)
+		}
+	}
+}
+
+func (s *Service) worker() {
+	for queuedJob := range s.queue {
+		s.run(queuedJob)
+	}
+}
+
+func (s *Service) run(runningJob *job) {
+	runningJob.mutex.Lock()
+	if runningJob.cancelled {
+		runningJob.mutex.Unlock()
+		return
+	}
+	runningJob.setStatus(StatusRunning)
+	runningJob.mutex.Unlock()
+
+	fmt.Printf("🚀 Running simulation job %s\n", runningJob.state.Id)
+	request := runningJob.request
+	result, err := s.debugService.SimulateRawTransactionWithProgress(runningJob, request.RawData, request.BlockNumber, request.TxIndex, request.StateOverrides)
+	runningJob.cancel()
+
+	runningJob.mutex.Lock()
+	defer runningJob.mutex.Unlock()
+
+	// The forks of a cancelled job were already deleted
+	if runningJob.cancelled {
+		return
+	}
+
+	if err != nil {
+		fmt.Printf("❌ Simulation job %s failed: %v\n", runningJob.state.Id, err)
+		runningJob.state.Error = err.Error()
+		runningJob.setStatus(StatusFailed)
+		return
+	}
+
+	fmt.Printf("✅ Simulation job %s completed\n", runningJob.state.Id)
+	runningJob.state.Result = &result
+	runningJob.emit(Event{Type: EventResult, Data: result})
+	runningJob.setStatus(StatusCompleted)
+}
+
+func (s *Service) deleteFork(forkId string) {
+	err := s.forkService.DeleteFork(forkId)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to delete fork %s of cancelled job: %v\n", forkId, err)
+	} else {
+		fmt.Printf("🧹 Cleaned up fork %s of cancelled job\n", forkId)
+	}
+}
+
+func (j *job) snapshot() Job {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.state
+}
+
+// Must be called with the job mutex held
+func (j *job) setStatus(status string) {
+	j.state.Status = status
+	j.state.UpdatedAt = time.Now()
+	j.emit(Event{Type: EventStatus, Status: status, Message: j.state.Error})
+}
+
+// Must be called with the job mutex held
+func (j *job) emit(event Event) {
+	event.Id = len(j.events) + 1
+	event.Time = time.Now()
+	j.events = append(j.events, event)
+
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// Must be called with the job mutex held
+func (j *job) isFinished() bool {
+	return j.state.Status != StatusQueued && j.state.Status != StatusRunning
+}
+
+func (j *job) SetStage(stage string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.state.Stage = stage
+	j.state.UpdatedAt = time.Now()
+	j.emit(Event{Type: EventStage, Stage: stage})
+}
+
+func (j *job) Log(message string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.logEvents++
+	switch {
+	case j.logEvents < maxLogEvents:
+		j.emit(Event{Type: EventLog, Stage: j.state.Stage, Message: message})
+	case j.logEvents == maxLogEvents:
+		j.emit(Event{Type: EventLog, Stage: j.state.Stage, Message: "Too many log messages, the following ones are omitted"})
+	}
+}
+
+func (j *job) SetPartialResult(name string, value interface{}) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.emit(Event{Type: EventPartialResult, Name: name, Data: value})
+}
+
+// A fork created after cancellation is deleted right away, the simulation stops at its next RPC call
+func (j *job) AddFork(forkId string) {
+	j.mutex.Lock()
+	cancelled := j.cancelled
+	if !cancelled {
+		j.forks = append(j.forks, forkId)
+	}
+	j.mutex.Unlock()
+
+	if cancelled {
+		j.service.deleteFork(forkId)
+	}
+}
+
+func (j *job) ReleaseFork(forkId string) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for i, jobFork := range j.forks {
+		if jobFork == forkId {
+			j.forks = append(j.forks[:i], j.forks[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (j *job) Context() context.Context {
+	return j.ctx
+}
@@ -0,0 +1,298 @@
+package jobs
+
+import (
+	"Simulations/src/debug"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Simulation creating forks, optionally running until the job is cancelled. Whether it still owned its
+// forks when it ended is sent on released.
+type fakeDebugService struct {
+	forks    []string
+	block    bool
+	started  chan struct{}
+	released chan []bool
+}
+
+func (f *fakeDebugService) SimulateRawTransactionWithProgress(progress debug.Progress, rawData []byte, blockNumber string, txIndex *int, stateOverrides debug.StateOverride) (debug.SimulationResult, error) {
+	for _, forkId := range f.forks {
+		progress.AddFork(forkId)
+	}
+	close(f.started)
+
+	if f.block {
+		<-progress.Context().Done()
+	}
+
+	var released []bool
+	for _, forkId := range f.forks {
+		released = append(released, progress.ReleaseFork(forkId))
+	}
+	f.released <- released
+
+	return debug.SimulationResult{}, progress.Context().Err()
+}
+
+// Fork service recording the deleted forks
+type fakeForkService struct {
+	deleted []string
+	mutex   sync.Mutex
+}
+
+func (f *fakeForkService) DeleteFork(forkId string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.deleted = append(f.deleted, forkId)
+	return nil
+}
+
+func (f *fakeForkService) getDeleted() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	deleted := append([]string{}, f.deleted...)
+	sort.Strings(deleted)
+	return deleted
+}
+
+func waitForStatus(t *testing.T, s *Service, jobId string, status string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		existingJob, err := s.GetJob(jobId)
+		if err == nil && existingJob.Status == status {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s never reached status %s", jobId, status)
+}
+
+func TestCancelJob(t *testing.T) {
+	tests := []struct {
+		name             string
+		workers          int
+		block            bool
+		finishFirst      bool
+		expectedStatus   string
+		expectedDeleted  []string
+		expectedReleased []bool // Nil when the simulation never runs
+	}{
+		{
+			name:           "queued job never runs",
+			workers:        0,
+			expectedStatus: StatusCancelled,
+		},
+		{
+			name:             "running job loses its forks to the cancellation",
+			workers:          1,
+			block:            true,
+			expectedStatus:   StatusCancelled,
+			expectedDeleted:  []string{"fork-1", "fork-2"},
+			expectedReleased: []bool{false, false},
+		},
+		{
+			name:             "finished job is left alone",
+			workers:          1,
+			finishFirst:      true,
+			expectedStatus:   StatusCompleted,
+			expectedReleased: []bool{true, true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			simulation := &fakeDebugService{
+				forks:    []string{"fork-1", "fork-2"},
+				block:    test.block,
+				started:  make(chan struct{}),
+				released: make(chan []bool, 1),
+			}
+			forks := &fakeForkService{}
+			s := NewService(simulation, forks, test.workers)
+
+			submitted, err := s.SubmitSimulation(SimulationRequest{})
+			if err != nil {
+				t.Fatalf("SubmitSimulation failed: %v", err)
+			}
+
+			if test.workers > 0 {
+				<-simulation.started
+			}
+			if test.finishFirst {
+				waitForStatus(t, s, submitted.Id, StatusCompleted)
+			}
+
+			cancelled, err := s.CancelJob(submitted.Id)
+			if err != nil {
+				t.Fatalf("CancelJob failed: %v", err)
+			}
+			if cancelled.Status != test.expectedStatus {
+				t.Errorf("status %s, expected %s", cancelled.Status, test.expectedStatus)
+			}
+
+			if test.expectedReleased != nil {
+				released := <-simulation.released
+				if !reflect.DeepEqual(released, test.expectedReleased) {
+					t.Errorf("released %v, expected %v", released, test.expectedReleased)
+				}
+			}
+
+			// The end of the simulation doesn't change the status of a cancelled job
+			existingJob, err := s.GetJob(submitted.Id)
+			if err != nil {
+				t.Fatalf("GetJob failed: %v", err)
+			}
+			if existingJob.Status != test.expectedStatus {
+				t.Errorf("status %s after the simulation ended, expected %s", existingJob.Status, test.expectedStatus)
+			}
+
+			deleted := forks.getDeleted()
+			if len(deleted)+len(test.expectedDeleted) > 0 && !reflect.DeepEqual(deleted, test.expectedDeleted) {
+				t.Errorf("deleted forks %v, expected %v", deleted, test.expectedDeleted)
+			}
+		})
+	}
+}
+
+func TestAddForkAfterCancellation(t *testing.T) {
+	forks := &fakeForkService{}
+	s := NewService(nil, forks, 0)
+	cancelledJob := &job{service: s, cancelled: true}
+
+	cancelledJob.AddFork("fork-1")
+
+	if deleted := forks.getDeleted(); !reflect.DeepEqual(deleted, []string{"fork-1"}) {
+		t.Errorf("deleted forks %v, expected the fork to be deleted right away", deleted)
+	}
+	if cancelledJob.ReleaseFork("fork-1") {
+		t.Error("ReleaseFork returned true for a fork the cancellation deleted")
+	}
+}
+
+// Job with events of the given types, registered in a service without workers
+func newTestJob(status string, eventTypes ...string) (*Service, *job) {
+	s := NewService(nil, &fakeForkService{}, 0)
+	testJob := &job{
+		service: s,
+		state:   Job{Id: "job-1", Status: status},
+		changed: make(chan struct{}),
+	}
+	for _, eventType := range eventTypes {
+		testJob.emit(Event{Type: eventType})
+	}
+	s.jobs[testJob.state.Id] = testJob
+
+	return s, testJob
+}
+
+func TestNextEvents(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           string
+		after            int
+		stop             bool
+		emitLater        bool
+		expectedIds      []int
+		expectedFinished bool
+	}{
+		{name: "events after the given id", status: StatusRunning, after: 1, expectedIds: []int{2, 3}},
+		{name: "negative id returns every event", status: StatusRunning, after: -5, expectedIds: []int{1, 2, 3}},
+		{name: "finished job without new events", status: StatusCompleted, after: 3, expectedFinished: true},
+		{name: "finished job still returns its last events", status: StatusCompleted, after: 2, expectedIds: []int{3}},
+		{name: "running job times out", status: StatusRunning, after: 3},
+		{name: "stopped by the client", status: StatusRunning, after: 3, stop: true},
+		{name: "wakes up on a new event", status: StatusRunning, after: 3, emitLater: true, expectedIds: []int{4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, testJob := newTestJob(test.status, EventStatus, EventStage, EventLog)
+
+			stop := make(chan struct{})
+			if test.stop {
+				close(stop)
+			}
+
+			// Without a new event the call only returns at the timeout, well after the event is added
+			timeout := 50 * time.Millisecond
+			if test.emitLater {
+				timeout = time.Minute
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					testJob.Log("later")
+				}()
+			}
+
+			events, finished, err := s.NextEvents(testJob.state.Id, test.after, stop, timeout)
+			if err != nil {
+				t.Fatalf("NextEvents failed: %v", err)
+			}
+
+			var ids []int
+			for _, event := range events {
+				ids = append(ids, event.Id)
+			}
+			if !reflect.DeepEqual(ids, test.expectedIds) {
+				t.Errorf("event ids %v, expected %v", ids, test.expectedIds)
+			}
+			if finished != test.expectedFinished {
+				t.Errorf("finished %v, expected %v", finished, test.expectedFinished)
+			}
+		})
+	}
+}
+
+func TestNextEventsUnknownJob(t *testing.T) {
+	s := NewService(nil, &fakeForkService{}, 0)
+
+	_, _, err := s.NextEvents("missing", 0, nil, time.Millisecond)
+	if err == nil {
+		t.Error("expected an error for an unknown job")
+	}
+}
+
+func TestLogCap(t *testing.T) {
+	tests := []struct {
+		name         string
+		messages     int
+		expectedLogs int
+		truncated    bool
+	}{
+		{name: "below the cap", messages: 10, expectedLogs: 10},
+		{name: "just below the cap", messages: maxLogEvents - 1, expectedLogs: maxLogEvents - 1},
+		{name: "reaching the cap", messages: maxLogEvents, expectedLogs: maxLogEvents, truncated: true},
+		{name: "well over the cap", messages: maxLogEvents * 3, expectedLogs: maxLogEvents, truncated: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, testJob := newTestJob(StatusRunning)
+
+			for i := 0; i < test.messages; i++ {
+				testJob.Log("message")
+			}
+
+			var logs []Event
+			for _, event := range testJob.events {
+				if event.Type == EventLog {
+					logs = append(logs, event)
+				}
+			}
+
+			if len(logs) != test.expectedLogs {
+				t.Fatalf("%d log events, expected %d", len(logs), test.expectedLogs)
+			}
+
+			truncated := logs[len(logs)-1].Message != "message"
+			if truncated != test.truncated {
+				t.Errorf("last log %q, expected truncation %v", logs[len(logs)-1].Message, test.truncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"Simulations/src/debug"
+	"time"
+)
+
+// Raw transaction simulation requested through a job
+type SimulationRequest struct {
+	RawData        []byte
+	BlockNumber    string
+	TxIndex        *int
+	StateOverrides debug.StateOverride
+}
+
+// Job state as reported to clients
+type Job struct {
+	Id        string                  `json:"id"`
+	Status    string                  `json:"status"`
+	Stage     string                  `json:"stage,omitempty"`
+	Result    *debug.SimulationResult `json:"result,omitempty"`
+	Error     string                  `json:"error,omitempty"`
+	CreatedAt time.Time               `json:"createdAt"`
+	UpdatedAt time.Time               `json:"updatedAt"`
+}
+
+// Step of a job streamed to clients, ids increase from 1 in the order events happened
+type Event struct {
+	Id      int         `json:"id"`
+	Type    string      `json:"type"`
+	Status  string      `json:"status,omitempty"`
+	Stage   string      `json:"stage,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Name    string      `json:"name,omitempty"` // Name of a partial result
+	Data    interface{} `json:"data,omitempty"`
+	Time    time.Time   `json:"time"`
+}
@@ -0,0 +1,288 @@
+package localsources
+
+import (
+	"Simulations/src/etherscan"
+	"Simulations/src/sources"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Service keeps sources registered by users for addresses, such as contracts freshly deployed on a fork
+// that no explorer has verified. They are stored as one JSON file per address in dir.
+type Service struct {
+	dir string
+}
+
+func NewService(dir string) *Service {
+	return &Service{dir: dir}
+}
+
+// Register converts the registration to the explorer format and stores it, replacing earlier sources of the address
+func (s *Service) Register(address string, registration Registration) (LocalSource, error) {
+	if !common.IsHexAddress(address) {
+		return LocalSource{}, errors.New("invalid address")
+	}
+
+	localSource, err := convertRegistration(registration)
+	if err != nil {
+		return LocalSource{}, err
+	}
+	localSource.Address = strings.ToLower(address)
+	localSource.RegisteredAt = time.Now().UTC()
+
+	rawData, err := json.Marshal(localSource)
+	if err != nil {
+		return LocalSource{}, err
+	}
+
+	err = s.write(localSource.Address, rawData)
+	if err != nil {
+		return LocalSource{}, err
+	}
+
+	fmt.Printf("📥 Registered %s sources of %s for %s\n", localSource.Kind, localSource.Info.ContractName, localSource.Address)
+	return localSource, nil
+}
+
+// Get returns the registered sources of an address
+func (s *Service) Get(address string) (LocalSource, bool) {
+	if !common.IsHexAddress(address) {
+		return LocalSource{}, false
+	}
+
+	rawData, err := os.ReadFile(s.filePath(address))
+	if err != nil {
+		return LocalSource{}, false
+	}
+
+	var localSource LocalSource
+	err = json.Unmarshal(rawData, &localSource)
+	if err != nil {
+		fmt.Printf("⚠️  Ignoring unreadable local sources of %s: %v\n", address, err)
+		return LocalSource{}, false
+	}
+
+	return localSource, true
+}
+
+// List returns the registered addresses, most recent first
+func (s *Service) List() ([]Entry, error) {
+	entries := []Entry{}
+
+	files, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		localSource, found := s.Get(strings.TrimSuffix(file.Name(), ".json"))
+		if !found {
+			continue
+		}
+
+		entries = append(entries, Entry{
+			Address:         localSource.Address,
+			Kind:            localSource.Kind,
+			ContractName:    localSource.Info.ContractName,
+			CompilerVersion: localSource.Info.CompilerVersion,
+			RegisteredAt:    localSource.RegisteredAt,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].RegisteredAt.After(entries[j].RegisteredAt)
+	})
+
+	return entries, nil
+}
+
+// Delete removes the registered sources of an address, reporting whether there were any
+func (s *Service) Delete(address string) (bool, error) {
+	if !common.IsHexAddress(address) {
+		return false, errors.New("invalid address")
+	}
+
+	err := os.Remove(s.filePath(address))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *Service) filePath(address string) string {
+	return filepath.Join(s.dir, strings.ToLower(address)+".json")
+}
+
+// Writes through a temporary file so readers never see a partial registration
+func (s *Service) write(address string, data []byte) error {
+	err := os.MkdirAll(s.dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(s.dir, "."+address+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(data)
+	if err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	err = tempFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), s.filePath(address))
+}
+
+func convertRegistration(registration Registration) (LocalSource, error) {
+	kinds := 0
+	for _, field := range []json.RawMessage{registration.Input, registration.Artifact, registration.BuildInfo} {
+		if len(field) > 0 {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return LocalSource{}, errors.New("exactly one of input, artifact and buildInfo is required")
+	}
+
+	switch {
+	case len(registration.Input) > 0:
+		return fromStandardJson(registration)
+	case len(registration.Artifact) > 0:
+		return fromFoundryArtifact(registration)
+	default:
+		return fromHardhatBuildInfo(registration)
+	}
+}
+
+func fromStandardJson(registration Registration) (LocalSource, error) {
+	if registration.CompilerVersion == "" || registration.ContractName == "" {
+		return LocalSource{}, errors.New("compilerVersion and contractName are required with a standard JSON input")
+	}
+
+	var input map[string]interface{}
+	err := json.Unmarshal(registration.Input, &input)
+	if err != nil {
+		return LocalSource{}, errors.New("input is not a standard JSON input")
+	}
+
+	if _, exists := input["sources"]; !exists {
+		return LocalSource{}, errors.New("input has no sources")
+	}
+
+	return LocalSource{
+		Kind: KindStandardJson,
+		Info: sourceCodeInfo(string(registration.Input), registration.CompilerVersion, registration.ContractName),
+	}, nil
+}
+
+// Foundry artifacts carry the compiler settings in their metadata, sources are only embedded with use_literal_content
+func fromFoundryArtifact(registration Registration) (LocalSource, error) {
+	var artifact foundryArtifact
+	err := json.Unmarshal(registration.Artifact, &artifact)
+	if err != nil {
+		return LocalSource{}, errors.New("artifact is not a Foundry artifact")
+	}
+
+	rawMetadata := []byte(artifact.Metadata)
+	if len(rawMetadata) == 0 || string(rawMetadata) == "null" {
+		rawMetadata = []byte(artifact.RawMetadata)
+	}
+
+	if len(rawMetadata) == 0 {
+		return LocalSource{}, errors.New("artifact has no compiler metadata, build with extra_output = [\"metadata\"]")
+	}
+
+	info, err := sources.SourceCodeInfoFromMetadata(rawMetadata, registration.Sources, registration.ContractName)
+	if err != nil {
+		return LocalSource{}, err
+	}
+
+	localSource := LocalSource{
+		Kind: KindFoundry,
+		Info: info,
+	}
+	if len(artifact.Abi) > 0 && string(artifact.Abi) != "null" {
+		localSource.Abi = string(artifact.Abi)
+	}
+
+	return localSource, nil
+}
+
+// Hardhat build info files hold the exact standard JSON input of a compilation and its output
+func fromHardhatBuildInfo(registration Registration) (LocalSource, error) {
+	var buildInfo hardhatBuildInfo
+	err := json.Unmarshal(registration.BuildInfo, &buildInfo)
+	if err != nil || buildInfo.SolcLongVersion == "" || len(buildInfo.Input) == 0 {
+		return LocalSource{}, errors.New("buildInfo is not a Hardhat build info file")
+	}
+
+	if registration.ContractName == "" {
+		return LocalSource{}, errors.New("contractName is required with a build info file")
+	}
+
+	localSource := LocalSource{
+		Kind: KindHardhat,
+		Info: sourceCodeInfo(string(buildInfo.Input), buildInfo.SolcLongVersion, registration.ContractName),
+	}
+
+	fileName, name := "", registration.ContractName
+	if index := strings.LastIndex(name, ":"); index >= 0 {
+		fileName, name = name[:index], name[index+1:]
+	}
+
+	for outputFile, contracts := range buildInfo.Output.Contracts {
+		contract, exists := contracts[name]
+		if exists && (fileName == "" || fileName == outputFile) && len(contract.Abi) > 0 {
+			localSource.Abi = string(contract.Abi)
+			break
+		}
+	}
+
+	return localSource, nil
+}
+
+// Registered sources are always standard JSON, versions are prefixed with v like on explorers
+func sourceCodeInfo(input string, compilerVersion string, contractName string) etherscan.SourceCodeInfo {
+	if !strings.HasPrefix(compilerVersion, "v") {
+		compilerVersion = "v" + compilerVersion
+	}
+
+	info := etherscan.SourceCodeInfo{
+		SourceCode:      input,
+		ContractName:    contractName,
+		CompilerVersion: compilerVersion,
+		IsStandardJSON:  true,
+	}
+
+	if index := strings.LastIndex(contractName, ":"); index >= 0 {
+		info.ContractFileName = contractName[:index]
+	}
+
+	return info
+}
@@ -0,0 +1,161 @@
+package localsources
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func TestConvertRegistration(t *testing.T) {
+	const content = "contract A {}"
+	const abi = `[{"type":"function","name":"a"}]`
+	const otherAbi = `[{"type":"function","name":"b"}]`
+	const input = `{"language":"Solidity","sources":{"src/A.sol":{"content":"contract A {}"}}}`
+
+	metadata := `{
+		"compiler": {"version": "0.8.20+commit.a1b79de6"},
+		"language": "Solidity",
+		"sources": {"src/A.sol": {"content": "` + content + `"}},
+		"settings": {"compilationTarget": {"src/A.sol": "A"}}
+	}`
+	buildInfo := `{
+		"solcLongVersion": "0.8.24+commit.e11b9ed9",
+		"input": ` + input + `,
+		"output": {"contracts": {
+			"src/A.sol": {"A": {"abi": ` + abi + `}},
+			"test/A.sol": {"A": {"abi": ` + otherAbi + `}}
+		}}
+	}`
+
+	tests := []struct {
+		name                 string
+		registration         Registration
+		expectedKind         string
+		expectedContractName string
+		expectedFileName     string
+		expectedVersion      string
+		expectedAbi          string
+		expectedInput        string // Checked when set
+		expectError          bool
+	}{
+		{
+			name:        "nothing to register",
+			expectError: true,
+		},
+		{
+			name:         "more than one kind",
+			registration: Registration{Input: json.RawMessage(input), BuildInfo: json.RawMessage(buildInfo)},
+			expectError:  true,
+		},
+		{
+			name:                 "standard JSON input",
+			registration:         Registration{Input: json.RawMessage(input), CompilerVersion: "0.8.24+commit.e11b9ed9", ContractName: "src/A.sol:A"},
+			expectedKind:         KindStandardJson,
+			expectedContractName: "src/A.sol:A",
+			expectedFileName:     "src/A.sol",
+			expectedVersion:      "v0.8.24+commit.e11b9ed9",
+			expectedInput:        input,
+		},
+		{
+			name:         "standard JSON input without compiler version",
+			registration: Registration{Input: json.RawMessage(input), ContractName: "A"},
+			expectError:  true,
+		},
+		{
+			name:         "standard JSON input without sources",
+			registration: Registration{Input: json.RawMessage(`{"language":"Solidity"}`), CompilerVersion: "0.8.24+commit.e11b9ed9", ContractName: "A"},
+			expectError:  true,
+		},
+		{
+			name:                 "Foundry artifact with metadata",
+			registration:         Registration{Artifact: json.RawMessage(`{"abi":` + abi + `,"metadata":` + metadata + `}`)},
+			expectedKind:         KindFoundry,
+			expectedContractName: "src/A.sol:A",
+			expectedFileName:     "src/A.sol",
+			expectedVersion:      "v0.8.20+commit.a1b79de6",
+			expectedAbi:          abi,
+		},
+		{
+			name:                 "Foundry artifact with raw metadata",
+			registration:         Registration{Artifact: json.RawMessage(`{"rawMetadata":` + strconv.Quote(metadata) + `}`)},
+			expectedKind:         KindFoundry,
+			expectedContractName: "src/A.sol:A",
+			expectedFileName:     "src/A.sol",
+			expectedVersion:      "v0.8.20+commit.a1b79de6",
+		},
+		{
+			name:         "Foundry artifact without metadata",
+			registration: Registration{Artifact: json.RawMessage(`{"abi":` + abi + `}`)},
+			expectError:  true,
+		},
+		{
+			name:                 "Hardhat build info with qualified name",
+			registration:         Registration{BuildInfo: json.RawMessage(buildInfo), ContractName: "test/A.sol:A"},
+			expectedKind:         KindHardhat,
+			expectedContractName: "test/A.sol:A",
+			expectedFileName:     "test/A.sol",
+			expectedVersion:      "v0.8.24+commit.e11b9ed9",
+			expectedAbi:          otherAbi,
+			expectedInput:        input,
+		},
+		{
+			name:         "Hardhat build info without contract name",
+			registration: Registration{BuildInfo: json.RawMessage(buildInfo)},
+			expectError:  true,
+		},
+		{
+			name:         "not a Hardhat build info",
+			registration: Registration{BuildInfo: json.RawMessage(`{"input":` + input + `}`), ContractName: "A"},
+			expectError:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localSource, err := convertRegistration(test.registration)
+			if test.expectError {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convertRegistration failed: %v", err)
+			}
+
+			info := localSource.Info
+			if localSource.Kind != test.expectedKind {
+				t.Errorf("kind %s, expected %s", localSource.Kind, test.expectedKind)
+			}
+			if info.ContractName != test.expectedContractName {
+				t.Errorf("contract name %s, expected %s", info.ContractName, test.expectedContractName)
+			}
+			if info.ContractFileName != test.expectedFileName {
+				t.Errorf("contract file %s, expected %s", info.ContractFileName, test.expectedFileName)
+			}
+			if info.CompilerVersion != test.expectedVersion {
+				t.Errorf("compiler version %s, expected %s", info.CompilerVersion, test.expectedVersion)
+			}
+			if !info.IsStandardJSON {
+				t.Error("registered sources should be a standard JSON input")
+			}
+			if localSource.Abi != test.expectedAbi {
+				t.Errorf("abi %s, expected %s", localSource.Abi, test.expectedAbi)
+			}
+			if test.expectedInput != "" && info.SourceCode != test.expectedInput {
+				t.Errorf("input %s, expected %s", info.SourceCode, test.expectedInput)
+			}
+
+			// Every kind has to give solc something to compile
+			var standardJsonInput struct {
+				Sources map[string]struct {
+					Content string `json:"content"`
+				} `json:"sources"`
+			}
+			err = json.Unmarshal([]byte(info.SourceCode), &standardJsonInput)
+			if err != nil || standardJsonInput.Sources["src/A.sol"].Content != content {
+				t.Errorf("input %s doesn't hold the source of src/A.sol", info.SourceCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package localsources
+
+import (
+	"Simulations/src/etherscan"
+	"encoding/json"
+	"time"
+)
+
+// Kinds of registered sources
+const (
+	KindStandardJson = "standardJson"
+	KindFoundry      = "foundry"
+	KindHardhat      = "hardhat"
+)
+
+// Sources registered for an address, exactly one of Input, Artifact and BuildInfo is set
+type Registration struct {
+	// Solc standard JSON input, compiled with CompilerVersion, e.g. 0.8.24+commit.e11b9ed9
+	Input           json.RawMessage `json:"input,omitempty"`
+	CompilerVersion string          `json:"compilerVersion,omitempty"`
+
+	// Foundry out/<File>.sol/<Contract>.json artifact, with the source files its metadata doesn't embed
+	Artifact json.RawMessage   `json:"artifact,omitempty"`
+	Sources  map[string]string `json:"sources,omitempty"`
+
+	// Hardhat artifacts/build-info/<id>.json file
+	BuildInfo json.RawMessage `json:"buildInfo,omitempty"`
+
+	// Plain or fully qualified as path:Name, taken from the Foundry artifact when empty
+	ContractName string `json:"contractName,omitempty"`
+}
+
+// Registered sources of an address, in the explorer format the debugger compiles
+type LocalSource struct {
+	Address      string                   `json:"address"`
+	Kind         string                   `json:"kind"`
+	Info         etherscan.SourceCodeInfo `json:"info"`
+	Abi          string                   `json:"abi,omitempty"`
+	RegisteredAt time.Time                `json:"registeredAt"`
+}
+
+// Registered address as listed by the API, without the sources
+type Entry struct {
+	Address         string    `json:"address"`
+	Kind            string    `json:"kind"`
+	ContractName    string    `json:"contractName"`
+	CompilerVersion string    `json:"compilerVersion"`
+	RegisteredAt    time.Time `json:"registeredAt"`
+}
+
+type foundryArtifact struct {
+	Abi         json.RawMessage `json:"abi"`
+	Metadata    json.RawMessage `json:"metadata"`
+	RawMetadata string          `json:"rawMetadata"`
+}
+
+type hardhatBuildInfo struct {
+	SolcLongVersion string          `json:"solcLongVersion"`
+	Input           json.RawMessage `json:"input"`
+	Output          struct {
+		Contracts map[string]map[string]struct {
+			Abi json.RawMessage `json:"abi"`
+		} `json:"contracts"`
+	} `json:"output"`
+}
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type forkService interface {
 	ForwardRpcRequest(forkId string, rawData []byte) (*http.Response, error)
+	ForwardUpstreamRpcRequest(rawData []byte) (*http.Response, error)
 }
 
 type Service struct {
@@ -93,6 +96,48 @@ func (s *Service) ChangeStorageSlot(forkId, tokenAddress, value, slot string) er
 	return nil
 }
 
+func (s *Service) SetCode(forkId, contractAddress, code string) error {
+	rpcRequest := RPCRequest{
+		JSONPRC: "2.0",
+		ID:      "1",
+		Method:  "anvil_setCode",
+		Params:  []string{contractAddress, code},
+	}
+
+	rawData, err := json.Marshal(rpcRequest)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = s.SendRpcRequest(forkId, rawData)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) SetNonce(forkId, address, nonce string) error {
+	rpcRequest := RPCRequest{
+		JSONPRC: "2.0",
+		ID:      "1",
+		Method:  "anvil_setNonce",
+		Params:  []string{address, nonce},
+	}
+
+	rawData, err := json.Marshal(rpcRequest)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = s.SendRpcRequest(forkId, rawData)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *Service) GetCurrentSnapshot(forkId string) (string, error) {
 	rpcReq := RPCRequest{
 		JSONPRC: "2.0",
@@ -237,9 +282,102 @@ func (s *Service) GetContractBytecode(forkId string, contractAddress string) (st
 	return rpcRes.Result, nil
 }
 
+func (s *Service) GetLogs(forkId, fromBlock, toBlock string, topics []interface{}) ([]Log, error) {
+	type LogFilter struct {
+		FromBlock string        `json:"fromBlock"`
+		ToBlock   string        `json:"toBlock"`
+		Topics    []interface{} `json:"topics,omitempty"`
+	}
+
+	rpcReq := struct {
+		JSONPRC string        `json:"jsonrpc"`
+		ID      string        `json:"id"`
+		Method  string        `json:"method"`
+		Params  []interface{} `json:"params"`
+	}{
+		JSONPRC: "2.0",
+		ID:      "7",
+		Method:  "eth_getLogs",
+		Params:  []interface{}{LogFilter{FromBlock: fromBlock, ToBlock: toBlock, Topics: topics}},
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return nil, err
+	}
+
+	_, resData, err := s.SendRpcRequest(forkId, rawData)
+	if err != nil {
+		return nil, err
+	}
+
+	var rpcRes RPCResponseLogs
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return nil, err
+	}
+
+	return rpcRes.Result, nil
+}
+
+// GetForkBlockNumber returns the upstream block the fork was created from
+func (s *Service) GetForkBlockNumber(forkId string) (uint64, error) {
+	rpcReq := RPCRequest{
+		JSONPRC: "2.0",
+		ID:      "8",
+		Method:  "anvil_nodeInfo",
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return 0, err
+	}
+
+	_, resData, err := s.SendRpcRequest(forkId, rawData)
+	if err != nil {
+		return 0, err
+	}
+
+	var rpcRes RPCResponseNodeInfo
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return 0, err
+	}
+
+	return rpcRes.Result.ForkConfig.ForkBlockNumber, nil
+}
+
+func (s *Service) GetStorageAt(forkId string, contractAddress string, slot string) (string, error) {
+	rpcReq := RPCRequest{
+		JSONPRC: "2.0",
+		ID:      "9",
+		Method:  "eth_getStorageAt",
+		Params:  []string{contractAddress, slot, "latest"},
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return "", err
+	}
+
+	_, resData, err := s.SendRpcRequest(forkId, rawData)
+	if err != nil {
+		return "", err
+	}
+
+	var rpcRes RPCResponse
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return "", err
+	}
+
+	return rpcRes.Result, nil
+}
+
 func (s *Service) GetTransactionTrace(forkId string, txHash string) ([]CallTrace, error) {
 	type TracerConfig struct {
-		Tracer string `json:"tracer"`
+		Tracer       string          `json:"tracer"`
+		TracerConfig map[string]bool `json:"tracerConfig"`
 	}
 
 	rpcReq := struct {
@@ -251,7 +389,7 @@ func (s *Service) GetTransactionTrace(forkId string, txHash string) ([]CallTrace
 		JSONPRC: "2.0",
 		ID:      fmt.Sprintf("call_%d", time.Now().UnixNano()), // Unique timestamp ID
 		Method:  "debug_traceTransaction",
-		Params:  []interface{}{txHash, TracerConfig{Tracer: "callTracer"}},
+		Params:  []interface{}{txHash, TracerConfig{Tracer: "callTracer", TracerConfig: map[string]bool{"withLog": true}}},
 	}
 
 	rawData, err := json.Marshal(rpcReq)
@@ -290,6 +428,22 @@ func (s *Service) GetTransactionTrace(forkId string, txHash string) ([]CallTrace
 	return flatTraces, nil
 }
 
+// ParseQuantity parses tracer quantities which can be hex strings or numbers
+func ParseQuantity(quantity interface{}) uint64 {
+	switch value := quantity.(type) {
+	case float64:
+		return uint64(value)
+	case string:
+		parsed, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 64)
+		if err != nil {
+			return 0
+		}
+		return parsed
+	}
+
+	return 0
+}
+
 // Helper function to flatten nested calls into a list
 func flattenCalls(trace CallTrace, result *[]CallTrace, depth int) {
 	trace.Depth = depth
@@ -301,6 +455,10 @@ func flattenCalls(trace CallTrace, result *[]CallTrace, depth int) {
 
 func (s *Service) GetOpcodeTrace(forkId string, txHash string) (DebugResult, error) {
 	// Use empty config object to get struct logs (default tracer)
+	return s.GetOpcodeTraceWithConfig(forkId, txHash, map[string]interface{}{})
+}
+
+func (s *Service) GetOpcodeTraceWithConfig(forkId string, txHash string, config map[string]interface{}) (DebugResult, error) {
 	rpcReq := struct {
 		JSONPRC string        `json:"jsonrpc"`
 		ID      string        `json:"id"`
@@ -310,7 +468,7 @@ func (s *Service) GetOpcodeTrace(forkId string, txHash string) (DebugResult, err
 		JSONPRC: "2.0",
 		ID:      fmt.Sprintf("opcode_%d", time.Now().UnixNano()), // Unique timestamp ID
 		Method:  "debug_traceTransaction",
-		Params:  []interface{}{txHash, map[string]interface{}{}}, // Empty config for default tracer
+		Params:  []interface{}{txHash, config},
 	}
 
 	rawData, err := json.Marshal(rpcReq)
@@ -334,8 +492,7 @@ func (s *Service) GetOpcodeTrace(forkId string, txHash string) (DebugResult, err
 	return rpcRes.Result, nil
 }
 
-func (s *Service) GetTransactionErrorMessage(forkId string, txHash string) (string, error) {
-	// Check transaction receipt first - much more efficient
+func (s *Service) GetTransactionReceipt(forkId string, txHash string) (TransactionReceipt, error) {
 	rpcReq := RPCRequest{
 		JSONPRC: "2.0",
 		ID:      "3",
@@ -345,46 +502,456 @@ func (s *Service) GetTransactionErrorMessage(forkId string, txHash string) (stri
 
 	rawData, err := json.Marshal(rpcReq)
 	if err != nil {
-		return "", err
+		return TransactionReceipt{}, err
 	}
 
 	_, resData, err := s.SendRpcRequest(forkId, rawData)
 	if err != nil {
-		return "", err
+		return TransactionReceipt{}, err
 	}
 
-	var receiptRes struct {
-		Result struct {
-			Status string `json:"status"`
-			Logs   []struct {
-				Topics []string `json:"topics"`
-				Data   string   `json:"data"`
-			} `json:"logs"`
-		} `json:"result"`
+	var rpcRes RPCResponseReceipt
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return TransactionReceipt{}, err
 	}
 
-	err = json.Unmarshal(resData, &receiptRes)
+	return rpcRes.Result, nil
+}
+
+func (s *Service) GetStateDiff(forkId string, txHash string) (PrestateDiff, error) {
+	type TracerConfig struct {
+		Tracer       string          `json:"tracer"`
+		TracerConfig map[string]bool `json:"tracerConfig"`
+	}
+
+	rpcReq := struct {
+		JSONPRC string        `json:"jsonrpc"`
+		ID      string        `json:"id"`
+		Method  string        `json:"method"`
+		Params  []interface{} `json:"params"`
+	}{
+		JSONPRC: "2.0",
+		ID:      fmt.Sprintf("prestate_%d", time.Now().UnixNano()), // Unique timestamp ID
+		Method:  "debug_traceTransaction",
+		Params:  []interface{}{txHash, TracerConfig{Tracer: "prestateTracer", TracerConfig: map[string]bool{"diffMode": true}}},
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return PrestateDiff{}, err
+	}
+
+	_, resData, err := s.SendRpcRequest(forkId, rawData)
+	if err != nil {
+		return PrestateDiff{}, err
+	}
+
+	var rpcRes RPCResponsePrestateDiff
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return PrestateDiff{}, err
+	}
+
+	return rpcRes.Result, nil
+}
+
+// GetTransactionErrorMessage returns the raw revert data of a failed transaction, or the halt reason when there is none
+func (s *Service) GetTransactionErrorMessage(forkId string, txHash string) (string, error) {
+	// Check transaction receipt first - much more efficient
+	receipt, err := s.GetTransactionReceipt(forkId, txHash)
 	if err != nil {
 		return "", err
 	}
 
 	// If transaction succeeded, no error
-	if receiptRes.Result.Status == "0x1" {
+	if receipt.Status == "0x1" {
 		return "", nil
 	}
 
-	// Transaction failed - try to get revert reason from logs
-	// Look for Error(string) event: keccak256("Error(string)") = 0x08c379a0...
-	for _, log := range receiptRes.Result.Logs {
-		if len(log.Topics) > 0 && log.Topics[0] == "0x08c379a0afcc32b1a39302f7cb8073359698411ab5fd6e3edb2c02c0b5fba8aa" {
-			// Decode the revert reason from log data
-			if len(log.Data) > 138 { // 0x + 64 chars (offset) + 64 chars (length) + at least 2 chars (data)
-				// Simple decode - skip offset and length, get string data
-				return log.Data, nil
-			}
-		}
+	// Revert data is only available as the output of the top level call
+	trace, err := s.GetTransactionTrace(forkId, txHash)
+	if err != nil {
+		return "", err
+	}
+
+	if len(trace) == 0 {
+		return "Transaction Failed", nil
+	}
+
+	if trace[0].Output != "" && trace[0].Output != "0x" {
+		return trace[0].Output, nil
+	}
+
+	if trace[0].Error != "" {
+		return trace[0].Error, nil
 	}
 
-	// No specific revert reason found
 	return "Transaction Failed", nil
 }
+
+// TraceCall runs an unsigned call with the default struct logger on top of the latest fork block
+func (s *Service) TraceCall(forkId string, call CallRequest, options TraceCallOptions, config map[string]interface{}) (DebugResult, error) {
+	resData, err := s.traceCall(forkId, call, options, config)
+	if err != nil {
+		return DebugResult{}, err
+	}
+
+	var rpcRes RPCResponseDebug
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return DebugResult{}, err
+	}
+
+	return rpcRes.Result, nil
+}
+
+// TraceCallFrames runs an unsigned call with the callTracer and returns the flattened call frames
+func (s *Service) TraceCallFrames(forkId string, call CallRequest, options TraceCallOptions) ([]CallTrace, error) {
+	config := map[string]interface{}{
+		"tracer":       "callTracer",
+		"tracerConfig": map[string]bool{"withLog": true},
+	}
+
+	resData, err := s.traceCall(forkId, call, options, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var rpcRes RPCResponseCallTrace
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return nil, err
+	}
+
+	var flatTraces []CallTrace
+	flattenCalls(rpcRes.Result, &flatTraces, 0)
+
+	return flatTraces, nil
+}
+
+// TraceCallStateDiff runs an unsigned call with the prestateTracer in diff mode
+func (s *Service) TraceCallStateDiff(forkId string, call CallRequest, options TraceCallOptions) (PrestateDiff, error) {
+	config := map[string]interface{}{
+		"tracer":       "prestateTracer",
+		"tracerConfig": map[string]bool{"diffMode": true},
+	}
+
+	resData, err := s.traceCall(forkId, call, options, config)
+	if err != nil {
+		return PrestateDiff{}, err
+	}
+
+	var rpcRes RPCResponsePrestateDiff
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return PrestateDiff{}, err
+	}
+
+	return rpcRes.Result, nil
+}
+
+func (s *Service) traceCall(forkId string, call CallRequest, options TraceCallOptions, config map[string]interface{}) ([]byte, error) {
+	traceConfig := make(map[string]interface{})
+	for key, value := range config {
+		traceConfig[key] = value
+	}
+
+	if len(options.StateOverrides) > 0 {
+		traceConfig["stateOverrides"] = options.StateOverrides
+	}
+
+	if options.BlockOverrides != nil {
+		traceConfig["blockOverrides"] = options.BlockOverrides
+	}
+
+	rpcReq := struct {
+		JSONPRC string        `json:"jsonrpc"`
+		ID      string        `json:"id"`
+		Method  string        `json:"method"`
+		Params  []interface{} `json:"params"`
+	}{
+		JSONPRC: "2.0",
+		ID:      fmt.Sprintf("tracecall_%d", time.Now().UnixNano()), // Unique timestamp ID
+		Method:  "debug_traceCall",
+		Params:  []interface{}{call, "latest", traceConfig},
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return nil, err
+	}
+
+	_, resData, err := s.SendRpcRequest(forkId, rawData)
+	if err != nil {
+		return nil, err
+	}
+
+	var rpcErr RPCResponseError
+	err = json.Unmarshal(resData, &rpcErr)
+	if err != nil {
+		return nil, err
+	}
+
+	if rpcErr.Error != nil {
+		return nil, fmt.Errorf("debug_traceCall failed: %s", rpcErr.Error.Message)
+	}
+
+	return resData, nil
+}
+
+// SendRawTransaction submits a signed transaction and returns its hash, rejected transactions return the node's error
+func (s *Service) SendRawTransaction(forkId string, rawTransaction string) (string, error) {
+	resData, err := s.sendRpcCall(forkId, "eth_sendRawTransaction", []interface{}{rawTransaction})
+	if err != nil {
+		return "", err
+	}
+
+	var rpcRes RPCResponse
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return "", err
+	}
+
+	return rpcRes.Result, nil
+}
+
+// SendTransaction submits an unsigned transaction, the sender has to be impersonated
+func (s *Service) SendTransaction(forkId string, call CallRequest) (string, error) {
+	resData, err := s.sendRpcCall(forkId, "eth_sendTransaction", []interface{}{call})
+	if err != nil {
+		return "", err
+	}
+
+	var rpcRes RPCResponse
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return "", err
+	}
+
+	return rpcRes.Result, nil
+}
+
+func (s *Service) SetAutomine(forkId string, enabled bool) error {
+	_, err := s.sendRpcCall(forkId, "evm_setAutomine", []interface{}{enabled})
+	return err
+}
+
+func (s *Service) SetAutoImpersonate(forkId string, enabled bool) error {
+	_, err := s.sendRpcCall(forkId, "anvil_autoImpersonateAccount", []interface{}{enabled})
+	return err
+}
+
+// Sends a JSON-RPC request and turns an error response into a Go error
+func (s *Service) sendRpcCall(forkId string, method string, params []interface{}) ([]byte, error) {
+	rpcReq := struct {
+		JSONPRC string        `json:"jsonrpc"`
+		ID      string        `json:"id"`
+		Method  string        `json:"method"`
+		Params  []interface{} `json:"params"`
+	}{
+		JSONPRC: "2.0",
+		ID:      fmt.Sprintf("%s_%d", method, time.Now().UnixNano()), // Unique timestamp ID
+		Method:  method,
+		Params:  params,
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return nil, err
+	}
+
+	_, resData, err := s.SendRpcRequest(forkId, rawData)
+	if err != nil {
+		return nil, err
+	}
+
+	var rpcErr RPCResponseError
+	err = json.Unmarshal(resData, &rpcErr)
+	if err != nil {
+		return nil, err
+	}
+
+	if rpcErr.Error != nil {
+		return nil, fmt.Errorf("%s failed: %s", method, rpcErr.Error.Message)
+	}
+
+	return resData, nil
+}
+
+// GetUpstreamBlock returns a block with its full transactions from the forked chain
+func (s *Service) GetUpstreamBlock(blockNumber uint64) (Block, error) {
+	rpcReq := struct {
+		JSONPRC string        `json:"jsonrpc"`
+		ID      string        `json:"id"`
+		Method  string        `json:"method"`
+		Params  []interface{} `json:"params"`
+	}{
+		JSONPRC: "2.0",
+		ID:      "1",
+		Method:  "eth_getBlockByNumber",
+		Params:  []interface{}{fmt.Sprintf("0x%x", blockNumber), true},
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return Block{}, err
+	}
+
+	res, err := s.forkService.ForwardUpstreamRpcRequest(rawData)
+	if err != nil {
+		return Block{}, err
+	}
+	defer res.Body.Close()
+
+	resData, err := io.ReadAll(res.Body)
+	if err != nil {
+		return Block{}, err
+	}
+
+	var rpcRes RPCResponseBlock
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return Block{}, err
+	}
+
+	if rpcRes.Result == nil {
+		return Block{}, fmt.Errorf("block %d not found", blockNumber)
+	}
+
+	return *rpcRes.Result, nil
+}
+
+// GetUpstreamTransaction looks up a transaction and its position on the forked chain
+func (s *Service) GetUpstreamTransaction(txHash string) (BlockTransaction, error) {
+	rpcReq := struct {
+		JSONPRC string        `json:"jsonrpc"`
+		ID      string        `json:"id"`
+		Method  string        `json:"method"`
+		Params  []interface{} `json:"params"`
+	}{
+		JSONPRC: "2.0",
+		ID:      "1",
+		Method:  "eth_getTransactionByHash",
+		Params:  []interface{}{txHash},
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return BlockTransaction{}, err
+	}
+
+	res, err := s.forkService.ForwardUpstreamRpcRequest(rawData)
+	if err != nil {
+		return BlockTransaction{}, err
+	}
+	defer res.Body.Close()
+
+	resData, err := io.ReadAll(res.Body)
+	if err != nil {
+		return BlockTransaction{}, err
+	}
+
+	var rpcRes RPCResponseTransaction
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return BlockTransaction{}, err
+	}
+
+	if rpcRes.Result == nil {
+		return BlockTransaction{}, fmt.Errorf("transaction %s not found", txHash)
+	}
+
+	if rpcRes.Result.BlockNumber == "" {
+		return BlockTransaction{}, fmt.Errorf("transaction %s is still pending", txHash)
+	}
+
+	return *rpcRes.Result, nil
+}
+
+// GetUpstreamTransactionReceipt returns the receipt of a mined transaction on the forked chain
+func (s *Service) GetUpstreamTransactionReceipt(txHash string) (TransactionReceipt, error) {
+	rpcReq := RPCRequest{
+		JSONPRC: "2.0",
+		ID:      "3",
+		Method:  "eth_getTransactionReceipt",
+		Params:  []string{txHash},
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return TransactionReceipt{}, err
+	}
+
+	res, err := s.forkService.ForwardUpstreamRpcRequest(rawData)
+	if err != nil {
+		return TransactionReceipt{}, err
+	}
+	defer res.Body.Close()
+
+	resData, err := io.ReadAll(res.Body)
+	if err != nil {
+		return TransactionReceipt{}, err
+	}
+
+	var rpcRes RPCResponseReceipt
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return TransactionReceipt{}, err
+	}
+
+	if rpcRes.Result.TransactionHash == "" {
+		return TransactionReceipt{}, fmt.Errorf("receipt of %s not found", txHash)
+	}
+
+	return rpcRes.Result, nil
+}
+
+// GetUpstreamCode returns the latest runtime code of a contract on the forked chain
+func (s *Service) GetUpstreamCode(contractAddress string) (string, error) {
+	rpcReq := RPCRequest{
+		JSONPRC: "2.0",
+		ID:      "3",
+		Method:  "eth_getCode",
+		Params:  []string{contractAddress, "latest"},
+	}
+
+	rawData, err := json.Marshal(rpcReq)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := s.forkService.ForwardUpstreamRpcRequest(rawData)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	resData, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var rpcRes RPCResponse
+	err = json.Unmarshal(resData, &rpcRes)
+	if err != nil {
+		return "", err
+	}
+
+	return rpcRes.Result, nil
+}
+
+func (s *Service) SetNextBlockTimestamp(forkId string, timestamp string) error {
+	_, err := s.sendRpcCall(forkId, "evm_setNextBlockTimestamp", []interface{}{timestamp})
+	return err
+}
+
+func (s *Service) SetCoinbase(forkId string, coinbase string) error {
+	_, err := s.sendRpcCall(forkId, "anvil_setCoinbase", []interface{}{coinbase})
+	return err
+}
+
+func (s *Service) SetNextBlockBaseFee(forkId string, baseFee string) error {
+	_, err := s.sendRpcCall(forkId, "anvil_setNextBlockBaseFeePerGas", []interface{}{baseFee})
+	return err
+}
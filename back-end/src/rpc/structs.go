@@ -31,14 +31,23 @@ type CallTrace struct {
 	From         string      `json:"from"`
 	To           string      `json:"to"`
 	Value        string      `json:"value"`
-	Gas          interface{} `json:"gas"`          // Can be string or number
-	GasUsed      interface{} `json:"gasUsed"`      // Can be string or number
+	Gas          interface{} `json:"gas"`     // Can be string or number
+	GasUsed      interface{} `json:"gasUsed"` // Can be string or number
 	Input        string      `json:"input"`
 	Output       string      `json:"output,omitempty"`
 	Error        string      `json:"error,omitempty"`
 	RevertReason string      `json:"revertReason,omitempty"`
 	Calls        []CallTrace `json:"calls,omitempty"`
-	Depth        int         `json:"-"` // Added for compatibility, not from JSON response
+	Logs         []CallLog   `json:"logs,omitempty"` // Only present with the withLog tracer option
+	Depth        int         `json:"-"`              // Added for compatibility, not from JSON response
+}
+
+// Log emitted inside a call frame, position is the number of subcalls made before it
+type CallLog struct {
+	Address  string      `json:"address"`
+	Topics   []string    `json:"topics"`
+	Data     string      `json:"data"`
+	Position interface{} `json:"position"` // Can be string or number
 }
 
 type RPCResponseCallTrace struct {
@@ -47,12 +56,15 @@ type RPCResponseCallTrace struct {
 
 // debug_traceTransaction response format
 type StructLogs struct {
-	Depth   int    `json:"depth"`
-	Gas     int    `json:"gas"`
-	GasCost int    `json:"gasCost"`
-	Op      string `json:"op"`
-	Pc      int    `json:"pc"`
-	Stack   []any  `json:"stack"`
+	Depth   int               `json:"depth"`
+	Gas     int               `json:"gas"`
+	GasCost int               `json:"gasCost"`
+	Op      string            `json:"op"`
+	Pc      int               `json:"pc"`
+	Stack   []any             `json:"stack"`
+	Memory  []string          `json:"memory,omitempty"`  // Only present with enableMemory
+	Storage map[string]string `json:"storage,omitempty"` // Storage read or written so far in the frame
+	Error   string            `json:"error,omitempty"`
 }
 type DebugResult struct {
 	Failed      bool         `json:"failed"`
@@ -63,3 +75,135 @@ type DebugResult struct {
 type RPCResponseDebug struct {
 	Result DebugResult `json:"result"`
 }
+
+// eth_getLogs response format
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+}
+type RPCResponseLogs struct {
+	Result []Log `json:"result"`
+}
+
+// anvil_nodeInfo response format
+type NodeInfo struct {
+	ForkConfig struct {
+		ForkUrl         string `json:"forkUrl"`
+		ForkBlockNumber uint64 `json:"forkBlockNumber"`
+	} `json:"forkConfig"`
+}
+type RPCResponseNodeInfo struct {
+	Result NodeInfo `json:"result"`
+}
+
+// eth_getTransactionReceipt response format
+type TransactionReceipt struct {
+	TransactionHash  string `json:"transactionHash"`
+	BlockNumber      string `json:"blockNumber"`
+	TransactionIndex string `json:"transactionIndex"`
+	Status           string `json:"status"`
+	GasUsed          string `json:"gasUsed"`
+	From             string `json:"from"`
+	To               string `json:"to"`
+	ContractAddress  string `json:"contractAddress"`
+	Logs             []Log  `json:"logs"`
+}
+type RPCResponseReceipt struct {
+	Result TransactionReceipt `json:"result"`
+}
+
+// debug_traceTransaction with prestateTracer in diff mode response format
+type AccountState struct {
+	Balance string            `json:"balance,omitempty"`
+	Nonce   uint64            `json:"nonce,omitempty"`
+	Code    string            `json:"code,omitempty"`
+	Storage map[string]string `json:"storage,omitempty"`
+}
+type PrestateDiff struct {
+	Pre  map[string]AccountState `json:"pre"`
+	Post map[string]AccountState `json:"post"`
+}
+type RPCResponsePrestateDiff struct {
+	Result PrestateDiff `json:"result"`
+}
+
+// debug_traceCall transaction format, quantities are hex encoded
+type CallRequest struct {
+	From                 string `json:"from,omitempty"`
+	To                   string `json:"to,omitempty"`
+	Data                 string `json:"data,omitempty"`
+	Value                string `json:"value,omitempty"`
+	Gas                  string `json:"gas,omitempty"`
+	Nonce                string `json:"nonce,omitempty"`
+	GasPrice             string `json:"gasPrice,omitempty"`
+	MaxFeePerGas         string `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas,omitempty"`
+}
+
+// Account state override, same format as geth's eth_call overrides
+type AccountOverride struct {
+	Balance   string            `json:"balance,omitempty"`
+	Nonce     string            `json:"nonce,omitempty"`
+	Code      string            `json:"code,omitempty"`
+	State     map[string]string `json:"state,omitempty"`
+	StateDiff map[string]string `json:"stateDiff,omitempty"`
+}
+type StateOverride map[string]AccountOverride
+
+// Block context override for debug_traceCall
+type BlockOverrides struct {
+	Number string `json:"number,omitempty"`
+	Time   string `json:"time,omitempty"`
+}
+
+// Overrides applied to a single debug_traceCall
+type TraceCallOptions struct {
+	StateOverrides StateOverride   `json:"stateOverrides,omitempty"`
+	BlockOverrides *BlockOverrides `json:"blockOverrides,omitempty"`
+}
+
+// JSON-RPC error response format
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+type RPCResponseError struct {
+	Error *RPCError `json:"error"`
+}
+
+// eth_getBlockByNumber with full transactions response format
+type BlockTransaction struct {
+	Hash                 string `json:"hash"`
+	BlockNumber          string `json:"blockNumber"`
+	TransactionIndex     string `json:"transactionIndex"`
+	Type                 string `json:"type"`
+	From                 string `json:"from"`
+	To                   string `json:"to"`
+	Input                string `json:"input"`
+	Value                string `json:"value"`
+	Gas                  string `json:"gas"`
+	Nonce                string `json:"nonce"`
+	GasPrice             string `json:"gasPrice"`
+	MaxFeePerGas         string `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas,omitempty"`
+}
+type Block struct {
+	Number        string             `json:"number"`
+	Hash          string             `json:"hash"`
+	Timestamp     string             `json:"timestamp"`
+	Miner         string             `json:"miner"`
+	BaseFeePerGas string             `json:"baseFeePerGas,omitempty"`
+	GasLimit      string             `json:"gasLimit"`
+	Transactions  []BlockTransaction `json:"transactions"`
+}
+type RPCResponseBlock struct {
+	Result *Block `json:"result"`
+}
+type RPCResponseTransaction struct {
+	Result *BlockTransaction `json:"result"`
+}
@@ -0,0 +1,104 @@
+package signatures
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Well known events, so the most common logs don't need a lookup
+var knownEvents = map[string]string{
+	"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef": "Transfer(address,address,uint256)",
+	"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925": "Approval(address,address,uint256)",
+	"0xe1fffcc4923d04b559f4d29a8bfc6cda04eb5b0d3c460751c2402c5c5cc9109c": "Deposit(address,uint256)",
+	"0x7fcf532c15f0a6db0bd6d0e038bea71d30d808c7d98cb3bf7268a95bf5081b65": "Withdrawal(address,uint256)",
+	"0x1c411e9a96e071241c2f21f7726b17ae89e3cab4c78be50e062b03a9fffbbad1": "Sync(uint112,uint112)",
+	"0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822": "Swap(address,uint256,uint256,uint256,uint256,address)",
+	"0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67": "Swap(address,address,int256,int256,uint160,uint128,int24)",
+	"0x8be0079c531659141344cd1fd0a4f28419497f9722a3daafe3b4186f6b6457e0": "OwnershipTransferred(address,address)",
+	"0xbc7cd75a20ee27fd9adebab32041f755214dbc6bffa90cc0225b39da2e5c2d3b": "Upgraded(address)",
+}
+
+type Service struct {
+	cache map[string]string
+	mutex sync.RWMutex
+}
+
+func NewService() *Service {
+	return &Service{cache: make(map[string]string)}
+}
+
+// GetEventSignature resolves an event topic to its text signature
+func (s *Service) GetEventSignature(topic string) (string, error) {
+	topic = strings.ToLower(topic)
+
+	if signature, exists := knownEvents[topic]; exists {
+		return signature, nil
+	}
+
+	s.mutex.RLock()
+	signature, exists := s.cache[topic]
+	s.mutex.RUnlock()
+	if exists {
+		if signature == "" {
+			return "", errors.New("unknown event signature")
+		}
+		return signature, nil
+	}
+
+	signature, err := lookupEventSignature(topic)
+	if err != nil {
+		return "", err
+	}
+
+	// Unknown topics are cached as well to avoid repeating the lookup
+	s.mutex.Lock()
+	s.cache[topic] = signature
+	s.mutex.Unlock()
+
+	if signature == "" {
+		return "", errors.New("unknown event signature")
+	}
+
+	return signature, nil
+}
+
+func lookupEventSignature(topic string) (string, error) {
+	url := fmt.Sprintf("https://www.4byte.directory/api/v1/event-signatures/?hex_signature=%v", topic)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	resData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var signatureRes SignatureResponse
+	err = json.Unmarshal(resData, &signatureRes)
+	if err != nil {
+		return "", err
+	}
+
+	if len(signatureRes.Results) == 0 {
+		return "", nil
+	}
+
+	// The oldest entry is the least likely to be a spam collision
+	oldest := signatureRes.Results[0]
+	for _, result := range signatureRes.Results {
+		if result.Id < oldest.Id {
+			oldest = result
+		}
+	}
+
+	return oldest.TextSignature, nil
+}
@@ -0,0 +1,11 @@
+package signatures
+
+// 4byte.directory API response structs
+type SignatureResponse struct {
+	Count   int `json:"count"`
+	Results []struct {
+		Id            int    `json:"id"`
+		TextSignature string `json:"text_signature"`
+		HexSignature  string `json:"hex_signature"`
+	} `json:"results"`
+}
@@ -0,0 +1,115 @@
+package sources
+
+import (
+	"Simulations/src/etherscan"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SourceCodeInfoFromMetadata turns a solc metadata.json and the source files it lists into the explorer format,
+// with a standard JSON input using the original settings. Files are looked up by name in contents, then by
+// keccak256 since providers don't always keep the original paths, and last embedded in the metadata.
+// contractName is taken from the compilation target when empty.
+func SourceCodeInfoFromMetadata(rawMetadata []byte, contents map[string]string, contractName string) (etherscan.SourceCodeInfo, error) {
+	var metadata Metadata
+	err := json.Unmarshal(rawMetadata, &metadata)
+	if err != nil || metadata.Compiler.Version == "" {
+		return etherscan.SourceCodeInfo{}, errors.New("invalid compiler metadata")
+	}
+
+	settings := make(map[string]interface{})
+	for key, value := range metadata.Settings {
+		settings[key] = value
+	}
+
+	// The compilation target names the contract but isn't a standard JSON setting
+	if target, ok := settings["compilationTarget"].(map[string]interface{}); ok {
+		for fileName, name := range target {
+			if contractName == "" {
+				contractName = fmt.Sprintf("%s:%v", fileName, name)
+			}
+		}
+	}
+	delete(settings, "compilationTarget")
+
+	if contractName == "" {
+		return etherscan.SourceCodeInfo{}, errors.New("metadata has no compilation target")
+	}
+
+	// Metadata libraries are keyed by file:Name, standard JSON groups them by file
+	if libraries, ok := settings["libraries"].(map[string]interface{}); ok {
+		grouped := make(map[string]map[string]interface{})
+		for key, address := range libraries {
+			fileName, name := "", key
+			if index := strings.LastIndex(key, ":"); index >= 0 {
+				fileName, name = key[:index], key[index+1:]
+			}
+			if grouped[fileName] == nil {
+				grouped[fileName] = make(map[string]interface{})
+			}
+			grouped[fileName][name] = address
+		}
+		settings["libraries"] = grouped
+	}
+
+	contentsByHash := make(map[string]string)
+	for _, content := range contents {
+		contentsByHash[crypto.Keccak256Hash([]byte(content)).Hex()] = content
+	}
+
+	inputSources := make(map[string]interface{})
+	for fileName, source := range metadata.Sources {
+		content, provided := contents[fileName]
+		if provided && source.Keccak256 != "" && !strings.EqualFold(crypto.Keccak256Hash([]byte(content)).Hex(), source.Keccak256) {
+			return etherscan.SourceCodeInfo{}, fmt.Errorf("source file %s doesn't match the metadata", fileName)
+		}
+		if !provided {
+			content, provided = contentsByHash[strings.ToLower(source.Keccak256)]
+		}
+		if !provided {
+			content = source.Content
+		}
+		if content == "" {
+			return etherscan.SourceCodeInfo{}, fmt.Errorf("missing source file %s", fileName)
+		}
+
+		inputSources[fileName] = map[string]interface{}{"content": content}
+	}
+
+	language := metadata.Language
+	if language == "" {
+		language = "Solidity"
+	}
+
+	input, err := json.Marshal(map[string]interface{}{
+		"language": language,
+		"sources":  inputSources,
+		"settings": settings,
+	})
+	if err != nil {
+		return etherscan.SourceCodeInfo{}, err
+	}
+
+	// Compiler versions as explorers report them
+	compilerVersion := "v" + strings.TrimPrefix(metadata.Compiler.Version, "v")
+	if strings.EqualFold(language, "Vyper") {
+		compilerVersion = "vyper:" + strings.TrimPrefix(metadata.Compiler.Version, "v")
+	}
+
+	info := etherscan.SourceCodeInfo{
+		SourceCode:      string(input),
+		ContractName:    contractName,
+		CompilerVersion: compilerVersion,
+		IsStandardJSON:  true,
+	}
+
+	if index := strings.LastIndex(contractName, ":"); index >= 0 {
+		info.ContractFileName = contractName[:index]
+	}
+
+	return info, nil
+}
@@ -0,0 +1,177 @@
+package sources
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSourceCodeInfoFromMetadata(t *testing.T) {
+	const content = "contract A {}"
+	const library = "library L {}"
+	keccak := crypto.Keccak256Hash([]byte(content)).Hex()
+	libraryKeccak := crypto.Keccak256Hash([]byte(library)).Hex()
+
+	metadata := func(language string, sources string, settings string) []byte {
+		return []byte(`{
+			"compiler": {"version": "0.8.20+commit.a1b79de6"},
+			"language": "` + language + `",
+			"sources": ` + sources + `,
+			"settings": ` + settings + `
+		}`)
+	}
+	defaultSources := `{"src/A.sol": {"keccak256": "` + keccak + `"}}`
+	defaultSettings := `{"compilationTarget": {"src/A.sol": "A"}, "optimizer": {"enabled": true, "runs": 200}}`
+
+	tests := []struct {
+		name                 string
+		rawMetadata          []byte
+		contents             map[string]string
+		contractName         string
+		expectedContractName string
+		expectedFileName     string
+		expectedVersion      string
+		expectedSources      map[string]string
+		expectedSettings     map[string]interface{}
+		expectError          bool
+	}{
+		{
+			name:                 "file found by path",
+			rawMetadata:          metadata("Solidity", defaultSources, defaultSettings),
+			contents:             map[string]string{"src/A.sol": content},
+			expectedContractName: "src/A.sol:A",
+			expectedFileName:     "src/A.sol",
+			expectedVersion:      "v0.8.20+commit.a1b79de6",
+			expectedSources:      map[string]string{"src/A.sol": content},
+			expectedSettings:     map[string]interface{}{"optimizer": map[string]interface{}{"enabled": true, "runs": float64(200)}},
+		},
+		{
+			name:                 "file found by keccak under another path",
+			rawMetadata:          metadata("Solidity", defaultSources, defaultSettings),
+			contents:             map[string]string{"A.sol": content},
+			expectedContractName: "src/A.sol:A",
+			expectedFileName:     "src/A.sol",
+			expectedVersion:      "v0.8.20+commit.a1b79de6",
+			expectedSources:      map[string]string{"src/A.sol": content},
+			expectedSettings:     map[string]interface{}{"optimizer": map[string]interface{}{"enabled": true, "runs": float64(200)}},
+		},
+		{
+			name:                 "file embedded in the metadata",
+			rawMetadata:          metadata("Solidity", `{"src/A.sol": {"keccak256": "`+keccak+`", "content": "`+content+`"}}`, defaultSettings),
+			expectedContractName: "src/A.sol:A",
+			expectedFileName:     "src/A.sol",
+			expectedVersion:      "v0.8.20+commit.a1b79de6",
+			expectedSources:      map[string]string{"src/A.sol": content},
+			expectedSettings:     map[string]interface{}{"optimizer": map[string]interface{}{"enabled": true, "runs": float64(200)}},
+		},
+		{
+			name:                 "given contract name wins over the compilation target",
+			rawMetadata:          metadata("Solidity", defaultSources, defaultSettings),
+			contents:             map[string]string{"src/A.sol": content},
+			contractName:         "B",
+			expectedContractName: "B",
+			expectedVersion:      "v0.8.20+commit.a1b79de6",
+			expectedSources:      map[string]string{"src/A.sol": content},
+			expectedSettings:     map[string]interface{}{"optimizer": map[string]interface{}{"enabled": true, "runs": float64(200)}},
+		},
+		{
+			name: "libraries are grouped by file",
+			rawMetadata: metadata(
+				"Solidity",
+				`{"src/A.sol": {"keccak256": "`+keccak+`"}, "src/L.sol": {"keccak256": "`+libraryKeccak+`"}}`,
+				`{"compilationTarget": {"src/A.sol": "A"}, "libraries": {"src/L.sol:L": "0x1111111111111111111111111111111111111111", "M": "0x2222222222222222222222222222222222222222"}}`,
+			),
+			contents:             map[string]string{"src/A.sol": content, "src/L.sol": library},
+			expectedContractName: "src/A.sol:A",
+			expectedFileName:     "src/A.sol",
+			expectedVersion:      "v0.8.20+commit.a1b79de6",
+			expectedSources:      map[string]string{"src/A.sol": content, "src/L.sol": library},
+			expectedSettings: map[string]interface{}{"libraries": map[string]interface{}{
+				"src/L.sol": map[string]interface{}{"L": "0x1111111111111111111111111111111111111111"},
+				"":          map[string]interface{}{"M": "0x2222222222222222222222222222222222222222"},
+			}},
+		},
+		{
+			name:                 "vyper compiler version",
+			rawMetadata:          metadata("Vyper", `{"A.vy": {"keccak256": "`+keccak+`"}}`, `{"compilationTarget": {"A.vy": "A"}}`),
+			contents:             map[string]string{"A.vy": content},
+			expectedContractName: "A.vy:A",
+			expectedFileName:     "A.vy",
+			expectedVersion:      "vyper:0.8.20+commit.a1b79de6",
+			expectedSources:      map[string]string{"A.vy": content},
+			expectedSettings:     map[string]interface{}{},
+		},
+		{
+			name:        "file doesn't match its keccak",
+			rawMetadata: metadata("Solidity", defaultSources, defaultSettings),
+			contents:    map[string]string{"src/A.sol": "contract B {}"},
+			expectError: true,
+		},
+		{
+			name:        "missing file",
+			rawMetadata: metadata("Solidity", defaultSources, defaultSettings),
+			contents:    map[string]string{"src/B.sol": "contract B {}"},
+			expectError: true,
+		},
+		{
+			name:        "no compilation target",
+			rawMetadata: metadata("Solidity", defaultSources, `{}`),
+			contents:    map[string]string{"src/A.sol": content},
+			expectError: true,
+		},
+		{
+			name:        "not a metadata file",
+			rawMetadata: []byte(`{"abi": []}`),
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info, err := SourceCodeInfoFromMetadata(test.rawMetadata, test.contents, test.contractName)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if info.ContractName != test.expectedContractName || info.ContractFileName != test.expectedFileName {
+				t.Errorf("contract = %s in %s, expected %s in %s", info.ContractName, info.ContractFileName, test.expectedContractName, test.expectedFileName)
+			}
+			if info.CompilerVersion != test.expectedVersion {
+				t.Errorf("compiler version = %s, expected %s", info.CompilerVersion, test.expectedVersion)
+			}
+			if !info.IsStandardJSON {
+				t.Errorf("expected a standard JSON input")
+			}
+
+			var input struct {
+				Sources map[string]struct {
+					Content string `json:"content"`
+				} `json:"sources"`
+				Settings map[string]interface{} `json:"settings"`
+			}
+			err = json.Unmarshal([]byte(info.SourceCode), &input)
+			if err != nil {
+				t.Fatalf("invalid standard JSON input: %v", err)
+			}
+
+			sources := make(map[string]string)
+			for fileName, source := range input.Sources {
+				sources[fileName] = source.Content
+			}
+			if !reflect.DeepEqual(sources, test.expectedSources) {
+				t.Errorf("sources = %v, expected %v", sources, test.expectedSources)
+			}
+			if !reflect.DeepEqual(input.Settings, test.expectedSettings) {
+				t.Errorf("settings = %v, expected %v", input.Settings, test.expectedSettings)
+			}
+		})
+	}
+}
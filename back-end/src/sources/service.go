@@ -0,0 +1,139 @@
+package sources
+
+import (
+	"Simulations/src/etherscan"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Provider of verified sources and ABIs, answering etherscan.ErrNotVerified for contracts it doesn't know
+type Provider interface {
+	GetSourceCodeInfo(address string) (etherscan.SourceCodeInfo, error)
+	GetAbi(address string) (string, error)
+}
+
+// Service asks its providers in order until one has the contract. Each provider has its own rate limit.
+type Service struct {
+	providers []rateLimitedProvider
+}
+
+type rateLimitedProvider struct {
+	name     string
+	provider Provider
+	limiter  *rateLimiter
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// AddProvider appends a provider to the lookup order, allowed requestsPerSecond requests, without limit when not positive
+func (s *Service) AddProvider(name string, provider Provider, requestsPerSecond float64) {
+	s.providers = append(s.providers, rateLimitedProvider{
+		name:     name,
+		provider: provider,
+		limiter:  newRateLimiter(requestsPerSecond),
+	})
+}
+
+// Providers returns the provider names in lookup order
+func (s *Service) Providers() []string {
+	names := make([]string, len(s.providers))
+	for i, provider := range s.providers {
+		names[i] = provider.name
+	}
+
+	return names
+}
+
+func (s *Service) GetSourceCodeInfo(address string) (etherscan.SourceCodeInfo, error) {
+	var info etherscan.SourceCodeInfo
+
+	err := s.lookup("source code", address, func(provider Provider) error {
+		var err error
+		info, err = provider.GetSourceCodeInfo(address)
+		return err
+	})
+
+	return info, err
+}
+
+func (s *Service) GetAbi(address string) (string, error) {
+	var abi string
+
+	err := s.lookup("ABI", address, func(provider Provider) error {
+		var err error
+		abi, err = provider.GetAbi(address)
+		return err
+	})
+
+	return abi, err
+}
+
+// A contract is only reported as not verified when every provider said so, a failing provider
+// makes the lookup fail instead so the answer isn't cached as unverified
+func (s *Service) lookup(what string, address string, request func(provider Provider) error) error {
+	var failure error
+
+	for _, provider := range s.providers {
+		provider.limiter.Wait()
+
+		err := request(provider.provider)
+		if err == nil {
+			if len(s.providers) > 1 {
+				fmt.Printf("📚 Found %s of %s on %s\n", what, address, provider.name)
+			}
+			return nil
+		}
+
+		if !errors.Is(err, etherscan.ErrNotVerified) {
+			fmt.Printf("⚠️  Failed to get %s of %s from %s: %v\n", what, address, provider.name, err)
+			failure = fmt.Errorf("%s: %w", provider.name, err)
+		}
+	}
+
+	if failure != nil {
+		return failure
+	}
+
+	return etherscan.ErrNotVerified
+}
+
+// Spaces requests evenly, callers wait for their turn
+type rateLimiter struct {
+	interval time.Duration
+	next     time.Time
+	mutex    sync.Mutex
+}
+
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	if requestsPerSecond <= 0 {
+		return &rateLimiter{}
+	}
+
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
+
+func (l *rateLimiter) Wait() {
+	time.Sleep(l.reserve(time.Now()))
+}
+
+// Reserves the next request slot, returning how long the request has to wait for it from now
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	if l.interval == 0 {
+		return 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+
+	return wait
+}
@@ -0,0 +1,202 @@
+package sources
+
+import (
+	"Simulations/src/etherscan"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name              string
+		requestsPerSecond float64
+		requests          []time.Duration // Request times after start
+		waits             []time.Duration
+	}{
+		{
+			name:              "no limit",
+			requestsPerSecond: 0,
+			requests:          []time.Duration{0, 0, 0},
+			waits:             []time.Duration{0, 0, 0},
+		},
+		{
+			name:              "first request doesn't wait",
+			requestsPerSecond: 1,
+			requests:          []time.Duration{0},
+			waits:             []time.Duration{0},
+		},
+		{
+			name:              "simultaneous requests are spaced",
+			requestsPerSecond: 50,
+			requests:          []time.Duration{0, 0, 0, 0},
+			waits:             []time.Duration{0, 20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond},
+		},
+		{
+			name:              "later request waits for the rest of the interval",
+			requestsPerSecond: 10,
+			requests:          []time.Duration{0, 30 * time.Millisecond},
+			waits:             []time.Duration{0, 70 * time.Millisecond},
+		},
+		{
+			name:              "idle time isn't saved up",
+			requestsPerSecond: 10,
+			requests:          []time.Duration{0, time.Second, time.Second},
+			waits:             []time.Duration{0, 0, 100 * time.Millisecond},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := newRateLimiter(test.requestsPerSecond)
+
+			for i, request := range test.requests {
+				wait := limiter.reserve(start.Add(request))
+				if wait != test.waits[i] {
+					t.Errorf("request %d waits %v, expected %v", i, wait, test.waits[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterConcurrentReserve(t *testing.T) {
+	limiter := newRateLimiter(50)
+	now := time.Unix(1700000000, 0)
+
+	waits := make(chan time.Duration, 5)
+	var waitGroup sync.WaitGroup
+	for caller := 0; caller < 5; caller++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			waits <- limiter.reserve(now)
+		}()
+	}
+	waitGroup.Wait()
+	close(waits)
+
+	// Every caller gets its own slot, whatever order they arrive in
+	seen := make(map[time.Duration]bool)
+	for wait := range waits {
+		seen[wait] = true
+	}
+	for slot := 0; slot < 5; slot++ {
+		wait := time.Duration(slot) * 20 * time.Millisecond
+		if !seen[wait] {
+			t.Errorf("no caller got the slot waiting %v", wait)
+		}
+	}
+}
+
+// Provider answering with a fixed ABI or error, recording whether it was asked
+type fakeProvider struct {
+	abi    string
+	err    error
+	called bool
+}
+
+func (p *fakeProvider) GetSourceCodeInfo(address string) (etherscan.SourceCodeInfo, error) {
+	p.called = true
+	return etherscan.SourceCodeInfo{ContractName: p.abi}, p.err
+}
+
+func (p *fakeProvider) GetAbi(address string) (string, error) {
+	p.called = true
+	return p.abi, p.err
+}
+
+func TestServiceLookup(t *testing.T) {
+	failure := errors.New("connection refused")
+
+	tests := []struct {
+		name           string
+		providers      []*fakeProvider
+		expectedAbi    string
+		expectedErr    error
+		expectedCalled []bool
+	}{
+		{
+			name:           "first provider with the contract wins",
+			providers:      []*fakeProvider{{abi: "first"}, {abi: "second"}},
+			expectedAbi:    "first",
+			expectedCalled: []bool{true, false},
+		},
+		{
+			name:           "falls back when not verified",
+			providers:      []*fakeProvider{{err: etherscan.ErrNotVerified}, {abi: "second"}},
+			expectedAbi:    "second",
+			expectedCalled: []bool{true, true},
+		},
+		{
+			name:           "falls back when a provider fails",
+			providers:      []*fakeProvider{{err: failure}, {abi: "second"}},
+			expectedAbi:    "second",
+			expectedCalled: []bool{true, true},
+		},
+		{
+			name:           "not verified when every provider says so",
+			providers:      []*fakeProvider{{err: etherscan.ErrNotVerified}, {err: etherscan.ErrNotVerified}},
+			expectedErr:    etherscan.ErrNotVerified,
+			expectedCalled: []bool{true, true},
+		},
+		{
+			name:           "a failure isn't reported as not verified",
+			providers:      []*fakeProvider{{err: failure}, {err: etherscan.ErrNotVerified}},
+			expectedErr:    failure,
+			expectedCalled: []bool{true, true},
+		},
+		{
+			name:        "no providers",
+			expectedErr: etherscan.ErrNotVerified,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := NewService()
+			for _, provider := range test.providers {
+				service.AddProvider("fake", provider, 0)
+			}
+
+			for _, method := range []string{"GetAbi", "GetSourceCodeInfo"} {
+				for _, provider := range test.providers {
+					provider.called = false
+				}
+
+				var result string
+				var err error
+				if method == "GetAbi" {
+					result, err = service.GetAbi("0x1111111111111111111111111111111111111111")
+				} else {
+					var info etherscan.SourceCodeInfo
+					info, err = service.GetSourceCodeInfo("0x1111111111111111111111111111111111111111")
+					result = info.ContractName
+				}
+
+				if test.expectedErr != nil {
+					if !errors.Is(err, test.expectedErr) {
+						t.Errorf("%s error = %v, expected %v", method, err, test.expectedErr)
+					}
+					if test.expectedErr == failure && errors.Is(err, etherscan.ErrNotVerified) {
+						t.Errorf("%s reported a failure as not verified", method)
+					}
+				} else if err != nil || result != test.expectedAbi {
+					t.Errorf("%s = %q, %v, expected %q", method, result, err, test.expectedAbi)
+				}
+
+				called := make([]bool, len(test.providers))
+				for i, provider := range test.providers {
+					called[i] = provider.called
+				}
+				if len(called) > 0 && !reflect.DeepEqual(called, test.expectedCalled) {
+					t.Errorf("%s asked providers %v, expected %v", method, called, test.expectedCalled)
+				}
+			}
+		})
+	}
+}
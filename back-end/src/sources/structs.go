@@ -0,0 +1,21 @@
+package sources
+
+import "encoding/json"
+
+// Solc metadata.json, as published by Sourcify and embedded in Foundry artifacts
+type Metadata struct {
+	Compiler struct {
+		Version string `json:"version"`
+	} `json:"compiler"`
+	Language string                        `json:"language"`
+	Settings map[string]interface{}        `json:"settings"`
+	Sources  map[string]MetadataSourceFile `json:"sources"`
+	Output   struct {
+		Abi json.RawMessage `json:"abi"`
+	} `json:"output"`
+}
+
+type MetadataSourceFile struct {
+	Keccak256 string `json:"keccak256"`
+	Content   string `json:"content"`
+}
@@ -0,0 +1,130 @@
+package sourcify
+
+import (
+	"Simulations/src/etherscan"
+	"Simulations/src/sources"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const metadataFile = "metadata.json"
+
+// Service reads verified contracts from a Sourcify server. Full matches and partial matches, whose
+// metadata hash differs from the deployed one, are both used since the source maps are the same.
+type Service struct {
+	apiUrl  string
+	chainId string
+}
+
+func NewService(apiUrl string, chainId string) *Service {
+	return &Service{
+		apiUrl:  strings.TrimSuffix(apiUrl, "/"),
+		chainId: chainId,
+	}
+}
+
+// GetSourceCodeInfo compiles the sources with the settings of the contract's metadata.json
+func (s *Service) GetSourceCodeInfo(address string) (etherscan.SourceCodeInfo, error) {
+	files, err := s.getFiles(address)
+	if err != nil {
+		return etherscan.SourceCodeInfo{}, err
+	}
+
+	var rawMetadata []byte
+	contents := make(map[string]string)
+	for _, file := range files.Files {
+		if file.Name == metadataFile {
+			rawMetadata = []byte(file.Content)
+			continue
+		}
+
+		contents[sourcePath(file)] = file.Content
+	}
+
+	// Without the metadata the sources can't be compiled, like for a contract that isn't verified
+	if rawMetadata == nil {
+		return etherscan.SourceCodeInfo{}, etherscan.ErrNotVerified
+	}
+
+	return sources.SourceCodeInfoFromMetadata(rawMetadata, contents, "")
+}
+
+func (s *Service) GetAbi(address string) (string, error) {
+	files, err := s.getFiles(address)
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files.Files {
+		if file.Name != metadataFile {
+			continue
+		}
+
+		var metadata sources.Metadata
+		err := json.Unmarshal([]byte(file.Content), &metadata)
+		if err != nil {
+			return "", err
+		}
+
+		if len(metadata.Output.Abi) == 0 {
+			return "", etherscan.ErrNotVerified
+		}
+
+		return string(metadata.Output.Abi), nil
+	}
+
+	return "", etherscan.ErrNotVerified
+}
+
+func (s *Service) getFiles(address string) (FilesResponse, error) {
+	url := s.apiUrl + "/files/any/" + s.chainId + "/" + address
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return FilesResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return FilesResponse{}, etherscan.ErrNotVerified
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return FilesResponse{}, errors.New("rate limited by Sourcify")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return FilesResponse{}, fmt.Errorf("Sourcify request failed with status %d", resp.StatusCode)
+	}
+
+	resData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return FilesResponse{}, err
+	}
+
+	var filesRes FilesResponse
+	err = json.Unmarshal(resData, &filesRes)
+	if err != nil {
+		return FilesResponse{}, err
+	}
+
+	if filesRes.Status != "full" && filesRes.Status != "partial" {
+		return FilesResponse{}, etherscan.ErrNotVerified
+	}
+
+	fmt.Printf("📄 Sourcify has a %s match for %s\n", filesRes.Status, address)
+	return filesRes, nil
+}
+
+// Sourcify stores sources under <match>/<chainId>/<address>/sources/ followed by their original path
+func sourcePath(file File) string {
+	if _, path, found := strings.Cut(file.Path, "/sources/"); found {
+		return path
+	}
+
+	return file.Name
+}
@@ -0,0 +1,13 @@
+package sourcify
+
+// Files of a verified contract as returned by /files/any
+type FilesResponse struct {
+	Status string `json:"status"` // full or partial
+	Files  []File `json:"files"`
+}
+
+type File struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
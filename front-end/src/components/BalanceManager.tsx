@@ -1,5 +1,5 @@
 import React, { useState } from 'react';
-import { forkService } from '../services/forkService';
+import { forkService, Portfolio } from '../services/forkService';
 
 export default function BalanceManager() {
   const [forkId, setForkId] = useState('');
@@ -7,6 +7,7 @@ export default function BalanceManager() {
   const [balance, setBalance] = useState('');
   const [tokenAddress, setTokenAddress] = useState('');
   const [currentBalance, setCurrentBalance] = useState<string | null>(null);
+  const [portfolio, setPortfolio] = useState<Portfolio | null>(null);
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
   const [success, setSuccess] = useState<string | null>(null);
@@ -32,7 +33,8 @@ export default function BalanceManager() {
           setIsLoading(false);
           return;
         }
-        result = await forkService.getERC20Balance(forkId, address, tokenAddress);
+        const erc20Balance = await forkService.getERC20Balance(forkId, address, tokenAddress);
+        result = `${erc20Balance.formattedBalance ?? erc20Balance.balance} ${erc20Balance.symbol}`.trim();
       }
       setCurrentBalance(result);
       setSuccess(`Balance retrieved successfully`);
@@ -43,6 +45,27 @@ export default function BalanceManager() {
     }
   };
 
+  const handleGetPortfolio = async () => {
+    if (!forkId || !address) {
+      setError('Fork ID and Address are required');
+      return;
+    }
+
+    setIsLoading(true);
+    setError(null);
+    setSuccess(null);
+
+    try {
+      const result = await forkService.getPortfolio(forkId, address);
+      setPortfolio(result);
+      setSuccess(`Portfolio retrieved successfully`);
+    } catch (err: any) {
+      setError(err.response?.data?.message || err.message || 'Failed to get portfolio');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
   const handleSetBalance = async () => {
     if (!forkId || !address || !balance) {
       setError('Fork ID, Address, and Balance are required');
@@ -212,9 +235,31 @@ export default function BalanceManager() {
             >
               {isLoading ? 'Setting...' : 'Set Balance'}
             </button>
+
+            <button
+              className="btn secondary"
+              onClick={handleGetPortfolio}
+              disabled={isLoading || !forkId || !address}
+            >
+              {isLoading ? 'Loading...' : 'Get Portfolio'}
+            </button>
           </div>
         </div>
 
+        {portfolio && (
+          <div className="terminal-container">
+            <h3 style={{ marginBottom: '1rem', color: 'var(--tn-cyan)' }}>Portfolio</h3>
+            <div style={{ fontSize: '0.875rem', color: 'var(--tn-fg)' }}>
+              <div>• {portfolio.native.formattedBalance} {portfolio.native.symbol}</div>
+              {portfolio.tokens.map((token) => (
+                <div key={token.tokenAddress}>
+                  • {token.formattedBalance ?? token.balance} {token.symbol || token.tokenAddress}
+                </div>
+              ))}
+            </div>
+          </div>
+        )}
+
         <div className="terminal-container">
           <h3 style={{ marginBottom: '1rem', color: 'var(--tn-cyan)' }}>Quick Balance Examples</h3>
           <div style={{ color: 'var(--tn-comment)', marginBottom: '1rem' }}>
@@ -18,6 +18,25 @@ export interface ContractCalled {
   }>;
 }
 
+export interface ERC20Balance {
+  tokenAddress: string;
+  symbol: string;
+  decimals?: number;
+  balance: string;
+  formattedBalance?: string;
+}
+
+export interface Portfolio {
+  address: string;
+  native: {
+    symbol: string;
+    decimals: number;
+    balance: string;
+    formattedBalance: string;
+  };
+  tokens: ERC20Balance[];
+}
+
 export interface CallTrace {
   Opcode: string;
   LineNumber: number;
@@ -25,6 +44,143 @@ export interface CallTrace {
   ContractAddress: string;
 }
 
+export interface Step {
+  index: number;
+  pc: number;
+  opcode: string;
+  gas: number;
+  gasCost: number;
+  depth: number;
+  frameId: number;
+  contractAddress: string;
+  storageAddress: string;
+  stack: string[];
+  memory?: string[];
+  storage?: { [slot: string]: string };
+  source?: {
+    file: string;
+    offset: number;
+    length: number;
+    lineNumber: number;
+    jumpType: string;
+  };
+  error?: string;
+}
+
+export interface StepSession {
+  sessionId: string;
+  txHash: string;
+  forkId: string;
+  totalSteps: number;
+  failed: boolean;
+  frames: Array<{
+    id: number;
+    parentId: number;
+    depth: number;
+    callType: string;
+    contractAddress: string;
+    storageAddress: string;
+  }>;
+}
+
+export interface StepPage {
+  sessionId: string;
+  steps: Step[];
+  nextCursor: number;
+  totalSteps: number;
+}
+
+export interface Variable {
+  name: string;
+  type: string;
+  location?: string;
+  stackIndex: number;
+  value: any;
+}
+
+export interface StepVariables {
+  step: number;
+  frameId: number;
+  contractAddress: string;
+  function: string;
+  parameters: Variable[];
+  returnValues: Variable[];
+  locals: Variable[];
+}
+
+export interface Breakpoint {
+  type: 'line' | 'function' | 'opcode' | 'revert';
+  file?: string;
+  line?: number;
+  address?: string;
+  selector?: string;
+  opcode?: string;
+  slot?: string;
+}
+
+export interface NavigationResult {
+  step: Step;
+  reason: 'breakpoint' | 'step' | 'end';
+  breakpoint?: Breakpoint;
+}
+
+export interface AccountOverride {
+  balance?: string;
+  nonce?: string;
+  code?: string;
+  // Only supported by call simulations
+  state?: { [slot: string]: string };
+  stateDiff?: { [slot: string]: string };
+  patchedSources?: { [fileName: string]: string };
+}
+
+export interface CallSimulation {
+  from?: string;
+  to?: string;
+  data?: string;
+  value?: string;
+  gas?: string;
+  blockNumber?: string;
+  txIndex?: number;
+  stateOverrides?: { [address: string]: AccountOverride };
+}
+
+export interface BundleTransaction {
+  rawTransaction?: string;
+  from?: string;
+  to?: string;
+  data?: string;
+  value?: string;
+  gas?: string;
+}
+
+export interface BundleSimulation {
+  transactions: BundleTransaction[];
+  blockNumber?: string;
+  txIndex?: number;
+  sameBlock?: boolean;
+  stopOnFailure?: boolean;
+  stateOverrides?: { [address: string]: AccountOverride };
+}
+
+export interface BundleTransactionResult {
+  index: number;
+  txHash?: string;
+  status: 'success' | 'reverted' | 'rejected' | 'skipped';
+  blockNumber?: number;
+  gasUsed: number;
+  revertReason?: string;
+  revert?: any;
+  error?: string;
+  logs: any[];
+  stateDiff: any[];
+}
+
+export interface BundleResult {
+  success: boolean;
+  transactions: BundleTransactionResult[];
+}
+
 export interface DebugResult {
   contractsCalled: ContractCalled[];
   errorLineNumber: number;
@@ -40,6 +196,63 @@ export interface SimulationResult {
   debugTrace: CallTrace[];
 }
 
+export interface SimulationJob {
+  id: string;
+  status: 'queued' | 'running' | 'completed' | 'failed' | 'cancelled';
+  stage?: 'forking' | 'executing' | 'tracing' | 'fetchingSources' | 'compiling';
+  result?: SimulationResult;
+  error?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface JobEvent {
+  id: number;
+  type: 'status' | 'stage' | 'log' | 'partialResult' | 'result';
+  status?: SimulationJob['status'];
+  stage?: SimulationJob['stage'];
+  message?: string;
+  name?: 'contractsCalled' | 'logs' | 'revert';
+  data?: any;
+  time: string;
+}
+
+export interface ByteRange {
+  start: number;
+  end: number;
+  reason?: 'library' | 'immutable' | 'metadata';
+}
+
+export interface VerificationReport {
+  address: string;
+  codeHash: string;
+  contract: string;
+  compilerVersion: string;
+  status: 'exact_match' | 'partial_match' | 'mismatch';
+  compiledLength: number;
+  deployedLength: number;
+  differences: ByteRange[] | null;
+  ignoredRanges: ByteRange[] | null;
+}
+
+// Exactly one of input, artifact and buildInfo
+export interface LocalSourcesRegistration {
+  input?: any;
+  compilerVersion?: string;
+  artifact?: any;
+  sources?: { [filename: string]: string };
+  buildInfo?: any;
+  contractName?: string;
+}
+
+export interface LocalSourcesEntry {
+  address: string;
+  kind: 'standardJson' | 'foundry' | 'hardhat';
+  contractName: string;
+  compilerVersion: string;
+  registeredAt: string;
+}
+
 export class ForkService {
   // Fork management
   async createFork(forkDuration: number = 30): Promise<Fork> {
@@ -63,7 +276,7 @@ export class ForkService {
     return response.data;
   }
 
-  async getERC20Balance(forkId: string, address: string, tokenAddress: string): Promise<string> {
+  async getERC20Balance(forkId: string, address: string, tokenAddress: string): Promise<ERC20Balance> {
     const response = await axios.post(`${API_BASE_URL}/fork/getERC20Balance/${forkId}?address=${address}&tokenAddress=${tokenAddress}`);
     return response.data;
   }
@@ -73,6 +286,11 @@ export class ForkService {
     return response.data;
   }
 
+  async getPortfolio(forkId: string, address: string): Promise<Portfolio> {
+    const response = await axios.get(`${API_BASE_URL}/fork/${forkId}/portfolio/${address}`);
+    return response.data;
+  }
+
   // Debug functionality
   async getContractsCalled(forkId: string, txHash: string): Promise<ContractCalled[]> {
     const response = await axios.get(`${API_BASE_URL}/debug/contractsCalled/${forkId}?txHash=${txHash}`);
@@ -84,20 +302,136 @@ export class ForkService {
     return response.data;
   }
 
+  async debugHistoricalTransaction(txHash: string): Promise<{ RevertReason: string; Revert?: any; LineNumber: number; DebugTrace: CallTrace[]; Logs: any[] }> {
+    const response = await axios.get(`${API_BASE_URL}/debug/tx/${txHash}`);
+    return response.data;
+  }
+
   async getSourceCode(contractAddress: string): Promise<{ [filename: string]: string }> {
     const response = await axios.get(`${API_BASE_URL}/debug/getSourceCode?contractAddress=${contractAddress}`);
     return response.data;
   }
 
+  // Compares the recompiled source with the code deployed upstream, or on the fork when given
+  async verifyBytecode(address: string, forkId?: string): Promise<VerificationReport> {
+    const query = forkId ? `?forkId=${forkId}` : '';
+    const response = await axios.get(`${API_BASE_URL}/debug/verify/${address}${query}`);
+    return response.data;
+  }
+
+  // Sources the debugger uses instead of the explorer's, e.g. for contracts deployed on a fork
+  async registerLocalSources(address: string, registration: LocalSourcesRegistration): Promise<LocalSourcesEntry> {
+    const response = await axios.post(`${API_BASE_URL}/debug/sources/${address}`, registration);
+    return response.data;
+  }
+
+  async listLocalSources(): Promise<LocalSourcesEntry[]> {
+    const response = await axios.get(`${API_BASE_URL}/debug/sources`);
+    return response.data;
+  }
+
+  async deleteLocalSources(address: string): Promise<string> {
+    const response = await axios.delete(`${API_BASE_URL}/debug/sources/${address}`);
+    return response.data;
+  }
+
+  async createStepSession(forkId: string, txHash: string, includeMemory: boolean = false): Promise<StepSession> {
+    const response = await axios.post(`${API_BASE_URL}/debug/steps/${forkId}?txHash=${txHash}&memory=${includeMemory}`);
+    return response.data;
+  }
+
+  async getSteps(sessionId: string, cursor: number = 0, limit: number = 100): Promise<StepPage> {
+    const response = await axios.get(`${API_BASE_URL}/debug/stepSessions/${sessionId}?cursor=${cursor}&limit=${limit}`);
+    return response.data;
+  }
+
+  async getVariables(sessionId: string, step: number): Promise<StepVariables> {
+    const response = await axios.get(`${API_BASE_URL}/debug/stepSessions/${sessionId}/variables?step=${step}`);
+    return response.data;
+  }
+
+  async navigate(
+    sessionId: string,
+    command: 'continue' | 'stepInto' | 'stepOver' | 'stepOut',
+    from: number,
+    breakpoints: Breakpoint[] = []
+  ): Promise<NavigationResult> {
+    const response = await axios.post(`${API_BASE_URL}/debug/stepSessions/${sessionId}/navigate`, { command, from, breakpoints });
+    return response.data;
+  }
+
   // Simulation functionality
-  async simulateRawTransaction(txData: any, blockNumber?: string): Promise<SimulationResult> {
-    const url = blockNumber 
-      ? `${API_BASE_URL}/simulate/simulateRawTx?blockNumber=${blockNumber}`
+  async simulateRawTransaction(
+    txData: any,
+    blockNumber?: string,
+    stateOverrides?: { [address: string]: AccountOverride },
+    txIndex?: number
+  ): Promise<SimulationResult> {
+    const params = new URLSearchParams();
+    if (blockNumber) params.append('blockNumber', blockNumber);
+    if (txIndex !== undefined) params.append('txIndex', txIndex.toString());
+    const query = params.toString();
+    const url = query
+      ? `${API_BASE_URL}/simulate/simulateRawTx?${query}`
       : `${API_BASE_URL}/simulate/simulateRawTx`;
-    const response = await axios.post(url, txData);
+    const response = await axios.post(url, stateOverrides ? { ...txData, stateOverrides } : txData);
+    return response.data;
+  }
+
+  async simulateCall(call: CallSimulation): Promise<SimulationResult> {
+    const response = await axios.post(`${API_BASE_URL}/simulate/call`, call);
+    return response.data;
+  }
+
+  async simulateBundle(bundle: BundleSimulation): Promise<BundleResult> {
+    const response = await axios.post(`${API_BASE_URL}/simulate/bundle`, bundle);
+    return response.data;
+  }
+
+  // Simulation jobs running in the background
+  async submitSimulation(
+    txData: any,
+    blockNumber?: string,
+    stateOverrides?: { [address: string]: AccountOverride },
+    txIndex?: number
+  ): Promise<SimulationJob> {
+    const params = new URLSearchParams();
+    if (blockNumber) params.append('blockNumber', blockNumber);
+    if (txIndex !== undefined) params.append('txIndex', txIndex.toString());
+    const query = params.toString();
+    const url = query ? `${API_BASE_URL}/simulate?${query}` : `${API_BASE_URL}/simulate`;
+    const response = await axios.post(url, stateOverrides ? { ...txData, stateOverrides } : txData);
+    return response.data;
+  }
+
+  async getJob(jobId: string): Promise<SimulationJob> {
+    const response = await axios.get(`${API_BASE_URL}/jobs/${jobId}`);
+    return response.data;
+  }
+
+  async cancelJob(jobId: string): Promise<SimulationJob> {
+    const response = await axios.delete(`${API_BASE_URL}/jobs/${jobId}`);
     return response.data;
   }
 
+  // Live job events, the stream closes itself once the job is over
+  subscribeToJobEvents(jobId: string, onEvent: (event: JobEvent) => void): EventSource {
+    const source = new EventSource(`${API_BASE_URL}/simulate/${jobId}/events`);
+    const types: JobEvent['type'][] = ['status', 'stage', 'log', 'partialResult', 'result'];
+    types.forEach((type) => {
+      source.addEventListener(type, (message) => {
+        const event: JobEvent = JSON.parse((message as MessageEvent).data);
+        onEvent(event);
+        if (event.type === 'status' && !['queued', 'running'].includes(event.status ?? '')) {
+          source.close();
+        }
+      });
+    });
+    return source;
+  }
+
+
+
   // Combined debug functionality (with auto fork creation)
   async debugTransactionWithAutoFork(txHash?: string, txData?: any, blockNumber?: string): Promise<DebugResult> {
     let contractsCalled: ContractCalled[];